@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// readCells reads a pattern in plaintext format, with a row of '.' for dead and 'O' for alive cells on each line.
+// Lines starting with '!' are kept as comments, written the way RLE files write them.
+func readCells(r io.Reader) (*pattern, error) {
+	var pat pattern
+	scanner := bufio.NewScanner(r)
+	y := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "!") {
+			pat.comments = append(pat.comments, "#C "+strings.TrimSpace(line[1:]))
+			continue
+		}
+		for x, c := range line {
+			switch c {
+			case 'O', 'o', '*':
+				pat.cells = append(pat.cells, cell{X: x, Y: y})
+			case '.':
+			default:
+				return nil, errors.New("unexpected " + strconv.QuoteRune(c) + " in plaintext pattern")
+			}
+		}
+		if len(line) > pat.width {
+			pat.width = len(line)
+		}
+		y++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	pat.height = y
+	return &pat, nil
+}
+
+// writeCells writes world in plaintext format, leaving out the dead cells at the end of each row.
+// Comments written for RLE files, such as "#N Glider", are written with '!' in place of the '#' and letter.
+func writeCells(w io.Writer, world *gol.World, comments []string) error {
+	out := bufio.NewWriter(w)
+	for _, comment := range comments {
+		out.WriteString("!" + commentText(comment) + "\n")
+	}
+	row := make([]byte, world.Width())
+	for y := 0; y < world.Height(); y++ {
+		end := 0
+		for x := range row {
+			row[x] = '.'
+			if world.Get(x, y) == gol.Alive {
+				row[x] = 'O'
+				end = x + 1
+			}
+		}
+		out.Write(row[:end])
+		out.WriteByte('\n')
+	}
+	return out.Flush()
+}
+
+//Returns the text of a comment line from an RLE file, without the '#' and the letter saying what kind it is
+func commentText(comment string) string {
+	if strings.HasPrefix(comment, "#") {
+		comment = comment[1:]
+		if len(comment) > 0 && comment[0] != ' ' {
+			comment = comment[1:]
+		}
+	}
+	return strings.TrimSpace(comment)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+//The version of the checkpoint format, which changes whenever older checkpoints can no longer be read
+const checkpointVersion = 1
+
+// checkpoint is everything needed to carry on a game from the start of a turn.
+// It is written as a gob, compressed with gzip.
+// The rule, boundary, backend and engine are kept by name so that checkpoints don't depend on how they're stored.
+type checkpoint struct {
+	Version int
+
+	Turn     int
+	Turns    int
+	Threads  int
+	Rule     string
+	Boundary string
+	Backend  string
+	Engine   string
+	MaxNodes int
+
+	DetectCycles bool
+	StopOnCycle  bool
+
+	Width, Height int
+	Rows          [][]byte
+	//Outside holds the live cells of unbounded engines that have left the world
+	Outside []cell
+
+	//Soup records how the world the game started with was generated, if it was a soup
+	Soup *checkpointSoup
+}
+
+//The soupParams of a game that started from a soup
+type checkpointSoup struct {
+	Density             float64
+	Seed                int64
+	Symmetry            string
+	X, Y, Width, Height int
+}
+
+// writeCheckpoint saves state, a game run with p, to path, along with soup if the game started from one.
+// The checkpoint is written next to path first and then moved over it,
+// so that a crash part way through leaves the last checkpoint as it was.
+func writeCheckpoint(path string, p golParams, soup soupParams, state gol.State) error {
+	c := checkpoint{
+		Version:  checkpointVersion,
+		Turn:     state.Turn,
+		Turns:    p.Turns,
+		Threads:  p.Threads,
+		Rule:     p.Rule.String(),
+		Boundary: p.Boundary.String(),
+		Backend:  p.Backend.String(),
+		Engine:   p.Engine.String(),
+		MaxNodes: p.MaxNodes,
+
+		DetectCycles: p.DetectCycles,
+		StopOnCycle:  p.StopOnCycle,
+
+		Width:   state.World.Width(),
+		Height:  state.World.Height(),
+		Outside: state.Outside,
+	}
+	for y := 0; y < c.Height; y++ {
+		c.Rows = append(c.Rows, state.World.Row(y))
+	}
+	if soup.on {
+		c.Soup = &checkpointSoup{
+			Density:  soup.density,
+			Seed:     soup.seed,
+			Symmetry: soup.symmetry.String(),
+			X:        soup.rect.x,
+			Y:        soup.rect.y,
+			Width:    soup.rect.width,
+			Height:   soup.rect.height,
+		}
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		_ = os.MkdirAll(dir, os.ModePerm)
+	}
+	temp := path + ".tmp"
+	file, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+	zipped := gzip.NewWriter(file)
+	err = gob.NewEncoder(zipped).Encode(c)
+	if err == nil {
+		err = zipped.Close()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// readCheckpoint reads a checkpoint written by writeCheckpoint, returning the params of the game
+// it was taken from along with the state to carry on from, and the soup the game started from if there was one.
+func readCheckpoint(path string) (golParams, gol.State, soupParams, error) {
+	var p golParams
+	var soup soupParams
+	fail := func(err error) (golParams, gol.State, soupParams, error) {
+		return p, gol.State{}, soup, fmt.Errorf("%s: %v", path, err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return p, gol.State{}, soup, err
+	}
+	defer file.Close()
+
+	c, err := decodeCheckpoint(file)
+	if err != nil {
+		return fail(err)
+	}
+	p = golParams{
+		Turns:       c.Turns,
+		Threads:     c.Threads,
+		ImageWidth:  c.Width,
+		ImageHeight: c.Height,
+		MaxNodes:    c.MaxNodes,
+
+		DetectCycles: c.DetectCycles,
+		StopOnCycle:  c.StopOnCycle,
+	}
+	for _, value := range []struct {
+		name string
+		set  func(string) error
+	}{
+		{c.Rule, p.Rule.Set},
+		{c.Boundary, p.Boundary.Set},
+		{c.Backend, p.Backend.Set},
+		{c.Engine, p.Engine.Set},
+	} {
+		if err := value.set(value.name); err != nil {
+			return fail(err)
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return fail(err)
+	}
+	if c.Turn < 0 || c.Turn > c.Turns {
+		return fail(fmt.Errorf("turn %d is outside of the %d turns of the game", c.Turn, c.Turns))
+	}
+	if c.Soup != nil {
+		soup = soupParams{
+			on:      true,
+			density: c.Soup.Density,
+			seed:    c.Soup.Seed,
+			rect:    rectangle{x: c.Soup.X, y: c.Soup.Y, width: c.Soup.Width, height: c.Soup.Height},
+		}
+		if err := soup.symmetry.Set(c.Soup.Symmetry); err != nil {
+			return fail(err)
+		}
+	}
+
+	world := gol.NewWorld(c.Width, c.Height)
+	for y, row := range c.Rows {
+		copy(world.Row(y), row)
+	}
+	return p, gol.State{Turn: c.Turn, World: world, Outside: c.Outside}, soup, nil
+}
+
+//Reads the gob inside a checkpoint and makes sure it holds a whole world
+func decodeCheckpoint(file *os.File) (checkpoint, error) {
+	var c checkpoint
+	zipped, err := gzip.NewReader(file)
+	if err != nil {
+		return c, errors.New("not a checkpoint")
+	}
+	if err := gob.NewDecoder(zipped).Decode(&c); err != nil {
+		return c, errors.New("not a checkpoint: " + err.Error())
+	}
+	if c.Version != checkpointVersion {
+		return c, errors.New("unsupported checkpoint version " + strconv.Itoa(c.Version))
+	}
+	if c.Width < 1 || c.Height < 1 || len(c.Rows) != c.Height {
+		return c, errors.New("checkpoint doesn't hold a whole world")
+	}
+	for _, row := range c.Rows {
+		if len(row) != c.Width {
+			return c, errors.New("checkpoint doesn't hold a whole world")
+		}
+	}
+	return c, nil
+}
+
+// saveCheckpoints writes a checkpoint of game to files.checkpoint every files.checkpointEvery until it finishes.
+func saveCheckpoints(p golParams, files fileParams, game *gol.Game) {
+	if files.checkpoint == "" || files.checkpointEvery <= 0 {
+		return
+	}
+	for {
+		select {
+		case <-game.Done():
+			return
+		case <-time.After(files.checkpointEvery):
+		}
+		state := game.State()
+		if err := writeCheckpoint(files.checkpoint, p, files.soup, state); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println("Checkpoint at turn", state.Turn, "written to", files.checkpoint)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/cluster"
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// addressList lets a list of addresses be given as a command line flag, separated by commas.
+type addressList []string
+
+func (a *addressList) String() string {
+	return strings.Join(*a, ",")
+}
+
+func (a *addressList) Set(s string) error {
+	*a = nil
+	for _, address := range strings.Split(s, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			*a = append(*a, address)
+		}
+	}
+	if len(*a) == 0 {
+		return errors.New("expected at least one address")
+	}
+	return nil
+}
+
+// serveWorker runs a worker process for games started with -workers, listening on address until it fails.
+func serveWorker(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Worker listening on", listener.Addr())
+	return cluster.Serve(listener)
+}
+
+// checkClusterFiles returns an error if files asks for something games run on worker processes can't do.
+func checkClusterFiles(files fileParams) error {
+	if len(files.workers) == 0 {
+		return errors.New("-spares can only be used with -workers")
+	}
+	if files.gif != "" || files.stats != "" || files.http != "" || files.view != viewOff {
+		return errors.New("-gif, -stats, -http and -view can't be used with -workers")
+	}
+	return nil
+}
+
+// gameOfLifeCluster runs the game from state on the worker processes in files.workers,
+// printing the number of alive cells every 2 seconds, and writes out the final world as gameOfLifeFiles does.
+// The workers in files.spares join the game to take over from any that fail, and rollbacks are printed.
+// Keys aren't read and no checkpoints are written.
+func gameOfLifeCluster(p golParams, files fileParams, state gol.State, d distributorChans) ([]cell, error) {
+	game, err := cluster.Start(p, state, files.workers)
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range files.spares {
+		if err := game.Join(address); err != nil {
+			fmt.Println("Spare worker", address, "can't be reached:", err)
+		}
+	}
+	fmt.Println("Workers:", len(files.workers), "Spares:", len(files.spares))
+	go func() {
+		rollbacks := 0
+		for {
+			select {
+			case <-game.Done():
+				return
+			case <-time.After(2 * time.Second):
+			}
+			if count, turn := game.Rollbacks(); count > rollbacks {
+				fmt.Println("A worker failed, rolled back to turn", turn)
+				rollbacks = count
+			}
+			turn, alive := game.Count()
+			fmt.Println("Turn:", turn, "Cells alive: ", alive)
+		}
+	}()
+
+	final, err := game.Wait()
+	if err != nil {
+		return nil, err
+	}
+	comments := []string{"#C After " + strconv.Itoa(p.Turns) + " turns"}
+	writeFinal(p, files, d, worldName(p), final.World, cell{}, comments)
+	return final.World.Alive(), nil
+}
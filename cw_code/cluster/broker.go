@@ -0,0 +1,446 @@
+package cluster
+
+import (
+	"errors"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// BatchTurns is how many turns the workers run between the broker's requests to them.
+// Requests for the world are answered between batches.
+// It and the other settings below are read by Start, so changing them doesn't affect games already running.
+var BatchTurns = 64
+
+// CheckpointTurns is roughly how many turns go by between the copies of the world the broker takes
+// to roll back to if a worker fails. Copies are only taken between batches.
+var CheckpointTurns = 256
+
+// HeartbeatEvery is how often the broker pings the workers while they run a batch,
+// and HeartbeatTimeout is how long they have to answer before they are taken to have failed.
+var (
+	HeartbeatEvery   = time.Second
+	HeartbeatTimeout = 5 * time.Second
+)
+
+// CallTimeout is how long the broker waits for workers to take or hand back their bands.
+var CallTimeout = 30 * time.Second
+
+//The settings a game was started with
+type settings struct {
+	batchTurns, checkpointTurns                   int
+	heartbeatEvery, heartbeatTimeout, callTimeout time.Duration
+}
+
+//A connection to a worker along with the address the other workers reach it on
+type worker struct {
+	address string
+	client  *rpc.Client
+}
+
+// Game is a world spread over worker processes by a broker, started by Start.
+// Its methods may be called from any goroutine while the game runs.
+//
+// If a worker stops answering, the game is rolled back to the last copy of the world the broker took
+// and handed out again between the workers that are left and any that have joined.
+// It only fails once there are no workers left.
+type Game struct {
+	p gol.Params
+	settings
+
+	//workers and epoch are only used by run once the game has started
+	workers []worker
+	//epoch counts the times the world has been handed out
+	epoch int
+
+	//requests are answered by run between batches
+	requests chan chan gol.State
+
+	//mu guards the turn and live cell count from the last batch, the workers waiting to join and the rollbacks
+	mu           sync.Mutex
+	turn         int
+	alive        int
+	joined       []worker
+	closed       bool
+	rollbacks    int
+	rolledBackTo int
+
+	done  chan bool
+	final gol.State
+	err   error
+}
+
+// Start hands out state, a game run with p, between the workers listening at addrs and runs it up to p.Turns.
+// Each worker gets a band of whole rows, from the top of the world down, and p.Threads is ignored.
+// It returns an error if a worker can't be reached or won't take its band, or if p can't be run by workers:
+// cycles, frames and stats aren't supported, nor is the cross-surface.
+func Start(p gol.Params, state gol.State, addrs []string) (*Game, error) {
+	world := state.World
+	p.ImageWidth, p.ImageHeight = world.Width(), world.Height()
+	if len(addrs) < 1 || len(addrs) > p.ImageHeight {
+		return nil, errors.New("there must be between 1 worker and one for each row of the world")
+	}
+	if state.Turn < 0 || state.Turn > p.Turns {
+		return nil, errors.New("the game can't be started from a turn outside of 0 to p.Turns")
+	}
+	//The workers would refuse their bands anyway, but this finds out before connecting to them
+	valid := p
+	valid.Threads = 1
+	if err := valid.Validate(); err != nil {
+		return nil, err
+	}
+	if p.Engine != gol.Workers || p.Boundary == gol.CrossSurface {
+		return nil, errors.New("workers in other processes can't run the " + p.Engine.String() + " engine on a " + p.Boundary.String() + " boundary")
+	}
+	if p.DetectCycles || p.StopOnCycle || p.FrameEvery > 0 || p.StatsEvery > 0 {
+		return nil, errors.New("cycles, frames and stats aren't supported by workers in other processes")
+	}
+	g := &Game{
+		p:        p,
+		settings: settings{BatchTurns, CheckpointTurns, HeartbeatEvery, HeartbeatTimeout, CallTimeout},
+		requests: make(chan chan gol.State),
+		turn:     state.Turn,
+		done:     make(chan bool),
+	}
+	for _, addr := range addrs {
+		client, err := rpc.Dial("tcp", addr)
+		if err != nil {
+			g.close()
+			return nil, err
+		}
+		g.workers = append(g.workers, worker{address: addr, client: client})
+	}
+	//The broker keeps its own copy to roll back to, in case the caller changes state
+	checkpoint := copyState(state)
+	if err := g.load(checkpoint); err != nil {
+		g.close()
+		return nil, err
+	}
+	g.alive = len(world.Alive())
+
+	go g.run(checkpoint)
+	return g, nil
+}
+
+//Returns a copy of state that shares nothing with it
+func copyState(state gol.State) gol.State {
+	world := gol.NewWorld(state.World.Width(), state.World.Height())
+	for y := 0; y < world.Height(); y++ {
+		copy(world.Row(y), state.World.Row(y))
+	}
+	return gol.State{Turn: state.Turn, World: world}
+}
+
+//Hands out the bands of state between the workers, from the top of the world down, in a new epoch
+func (g *Game) load(state gol.State) error {
+	g.epoch++
+	n := len(g.workers)
+	//The rows are shared out as evenly as they can be, with the first workers taking any left over
+	rows, remainder := g.p.ImageHeight/n, g.p.ImageHeight%n
+	var calls []*rpc.Call
+	start := 0
+	for i, worker := range g.workers {
+		height := rows
+		if i < remainder {
+			height++
+		}
+		args := LoadArgs{
+			Epoch:    g.epoch,
+			Turn:     state.Turn,
+			Width:    g.p.ImageWidth,
+			Height:   g.p.ImageHeight,
+			Rule:     g.p.Rule.String(),
+			Boundary: g.p.Boundary.String(),
+			Backend:  g.p.Backend.String(),
+			Start:    start,
+			Above:    g.workers[(i+n-1)%n].address,
+			Below:    g.workers[(i+1)%n].address,
+		}
+		for y := start; y < start+height; y++ {
+			args.Rows = append(args.Rows, state.World.Row(y))
+		}
+		calls = append(calls, worker.client.Go("Worker.Load", args, new(bool), nil))
+		start += height
+	}
+	return wait(calls, g.callTimeout)
+}
+
+//Waits for every call to finish, returning the first error or giving up after timeout
+func wait(calls []*rpc.Call, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for _, call := range calls {
+		select {
+		case <-call.Done:
+			if call.Error != nil {
+				return call.Error
+			}
+		case <-timer.C:
+			return errors.New("a worker took too long to answer")
+		}
+	}
+	return nil
+}
+
+//Waits for every call to finish, pinging the workers every heartbeatEvery in the meantime,
+//and returns the first error or an error if a worker stops answering pings
+func (g *Game) watch(calls []*rpc.Call) error {
+	ticker := time.NewTicker(g.heartbeatEvery)
+	defer ticker.Stop()
+	for _, call := range calls {
+		for finished := false; !finished; {
+			select {
+			case <-call.Done:
+				if call.Error != nil {
+					return call.Error
+				}
+				finished = true
+			case <-ticker.C:
+				if lost := ping(g.workers, g.heartbeatTimeout); len(lost) > 0 {
+					return errors.New("the worker at " + lost[0].address + " stopped answering")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//Pings workers all at once, returning those that don't answer within timeout
+func ping(workers []worker, timeout time.Duration) []worker {
+	calls := make([]*rpc.Call, len(workers))
+	for i, worker := range workers {
+		calls[i] = worker.client.Go("Worker.Ping", true, new(bool), nil)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	timedOut := false
+	var lost []worker
+	for i, call := range calls {
+		answered := false
+		if timedOut {
+			select {
+			case <-call.Done:
+				answered = true
+			default:
+			}
+		} else {
+			select {
+			case <-call.Done:
+				answered = true
+			case <-timer.C:
+				timedOut = true
+			}
+		}
+		if !answered || call.Error != nil {
+			lost = append(lost, workers[i])
+		}
+	}
+	return lost
+}
+
+//Closes the connections to the workers, including those that never got a band
+func (g *Game) close() {
+	g.mu.Lock()
+	joined := g.joined
+	g.joined, g.closed = nil, true
+	g.mu.Unlock()
+	for _, worker := range append(g.workers, joined...) {
+		worker.client.Close()
+	}
+}
+
+//Runs the workers in batches until every turn is done, answering requests in between and
+//rolling back to checkpoint whenever a worker fails
+func (g *Game) run(checkpoint gol.State) {
+	defer close(g.done)
+	defer g.close()
+	turn := checkpoint.Turn
+	for {
+		var err error
+		if turn == g.p.Turns {
+			if g.final, err = g.gather(); err == nil {
+				return
+			}
+		} else {
+			select {
+			case request := <-g.requests:
+				var state gol.State
+				if state, err = g.gather(); err == nil {
+					checkpoint = state
+				}
+				//If the workers can't hand the world back it is rolled back to checkpoint anyway
+				request <- copyState(checkpoint)
+			default:
+			}
+			if err == nil {
+				turn, err = g.step(turn)
+			}
+			if err == nil && turn < g.p.Turns && turn-checkpoint.Turn >= g.checkpointTurns {
+				var state gol.State
+				if state, err = g.gather(); err == nil {
+					checkpoint = state
+				}
+			}
+		}
+
+		if err != nil {
+			if err = g.reassign(checkpoint); err != nil {
+				g.err = err
+				return
+			}
+			turn = checkpoint.Turn
+			g.mu.Lock()
+			g.turn, g.alive = turn, len(checkpoint.World.Alive())
+			g.rollbacks++
+			g.rolledBackTo = turn
+			g.mu.Unlock()
+		}
+	}
+}
+
+//Runs the next batch of turns from turn, returning the turn the workers got to
+func (g *Game) step(turn int) (int, error) {
+	batch := g.batchTurns
+	if g.p.Turns-turn < batch {
+		batch = g.p.Turns - turn
+	}
+	replies := make([]StepReply, len(g.workers))
+	calls := make([]*rpc.Call, len(g.workers))
+	for i, worker := range g.workers {
+		calls[i] = worker.client.Go("Worker.Step", StepArgs{Epoch: g.epoch, Turns: batch}, &replies[i], nil)
+	}
+	if err := g.watch(calls); err != nil {
+		return turn, err
+	}
+	turn += batch
+	alive := 0
+	for _, reply := range replies {
+		alive += reply.Alive
+	}
+	g.mu.Lock()
+	g.turn, g.alive = turn, alive
+	g.mu.Unlock()
+	return turn, nil
+}
+
+//Drops the workers that have stopped answering and hands checkpoint out again between the rest and any that
+//have joined. It keeps going for as long as workers are found to have failed while it does so,
+//and returns an error if none are left.
+func (g *Game) reassign(checkpoint gol.State) error {
+	var err error
+	for {
+		g.mu.Lock()
+		workers := append(g.workers, g.joined...)
+		g.joined = nil
+		g.mu.Unlock()
+
+		lost := ping(workers, g.heartbeatTimeout)
+		//Nothing more can be done if handing the world out failed again without losing a worker
+		if err != nil && len(lost) == 0 {
+			g.workers = workers
+			return err
+		}
+		g.workers = nil
+		for _, worker := range workers {
+			failed := false
+			for _, l := range lost {
+				failed = failed || l == worker
+			}
+			//Spare workers are kept in reserve if there are more than rows
+			if failed {
+				worker.client.Close()
+			} else if len(g.workers) == g.p.ImageHeight {
+				g.mu.Lock()
+				g.joined = append(g.joined, worker)
+				g.mu.Unlock()
+			} else {
+				g.workers = append(g.workers, worker)
+			}
+		}
+		if len(g.workers) == 0 {
+			return errors.New("every worker has failed")
+		}
+		if err = g.load(checkpoint); err == nil {
+			return nil
+		}
+	}
+}
+
+//Puts the world back together from the bands held by the workers
+func (g *Game) gather() (gol.State, error) {
+	replies := make([]RowsReply, len(g.workers))
+	calls := make([]*rpc.Call, len(g.workers))
+	for i, worker := range g.workers {
+		calls[i] = worker.client.Go("Worker.Rows", true, &replies[i], nil)
+	}
+	if err := wait(calls, g.callTimeout); err != nil {
+		return gol.State{}, err
+	}
+	state := gol.State{Turn: replies[0].Turn, World: gol.NewWorld(g.p.ImageWidth, g.p.ImageHeight)}
+	for _, reply := range replies {
+		for y, row := range reply.Rows {
+			copy(state.World.Row(reply.Start+y), row)
+		}
+	}
+	return state, nil
+}
+
+// Join adds the worker listening at address to those the broker can hand bands to.
+// It gets a band the next time the world is handed out, which happens when another worker fails.
+// It returns an error if the worker can't be reached.
+func (g *Game) Join(address string) error {
+	client, err := rpc.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		client.Close()
+		return errors.New("the game has finished")
+	}
+	g.joined = append(g.joined, worker{address: address, client: client})
+	return nil
+}
+
+// Wait blocks until every turn has been simulated and returns the final state,
+// or an error if every worker failed.
+func (g *Game) Wait() (gol.State, error) {
+	<-g.done
+	return g.final, g.err
+}
+
+// Done is closed once the game has finished or failed.
+func (g *Game) Done() <-chan bool {
+	return g.done
+}
+
+// State returns the game as it stands at the end of the current batch of turns,
+// or as it ended if it has finished.
+// If a worker fails while handing back its band, the world the game is rolled back to is returned.
+func (g *Game) State() (gol.State, error) {
+	request := make(chan gol.State, 1)
+	select {
+	case g.requests <- request:
+		return <-request, nil
+	case <-g.done:
+		return g.Wait()
+	}
+}
+
+// Count returns the turn the workers last got to along with the number of cells alive at the start of it.
+func (g *Game) Count() (turn, alive int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.turn, g.alive
+}
+
+// Rollbacks returns the number of times the game has been rolled back after a worker failed,
+// and the turn it was last rolled back to.
+func (g *Game) Rollbacks() (count, turn int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rollbacks, g.rolledBackTo
+}
@@ -0,0 +1,287 @@
+// Package cluster spreads a world over worker processes that exchange the rows on the edges of their bands over TCP.
+// A broker plays the part of the distributor, handing each worker its band and telling them all how many turns to run.
+// Workers send their edge rows straight to the workers above and below them with net/rpc.
+package cluster
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"strconv"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// HaloTimeout is how long a worker waits for the rows from its neighbours before giving up on a turn.
+var HaloTimeout = 30 * time.Second
+
+// LoadArgs hands a worker its band of the world, at the start of Turn.
+// The rule, boundary and backend are given by name, see gol.Rule.String.
+type LoadArgs struct {
+	//Epoch goes up every time the broker hands out the world again, so that rows from before then are turned away
+	Epoch         int
+	Turn          int
+	Width, Height int
+	Rule          string
+	Boundary      string
+	Backend       string
+	Start         int
+	Rows          [][]byte
+	//Above and Below are the addresses of the workers holding the rows above and below the band,
+	//wrapping around from the bottom of the world to the top
+	Above, Below string
+}
+
+// HaloArgs is a row sent to a worker by the neighbour above or below it, from the start of Turn.
+type HaloArgs struct {
+	Epoch     int
+	Turn      int
+	FromAbove bool
+	Row       []byte
+}
+
+// StepArgs tells a worker how many turns to run of the world it was handed in Epoch.
+type StepArgs struct {
+	Epoch int
+	Turns int
+}
+
+// StepReply is the turn a worker got to and the number of live cells in its band at the start of it.
+type StepReply struct {
+	Turn  int
+	Alive int
+}
+
+// RowsReply is the band held by a worker at the start of Turn, which starts at row Start of the world.
+type RowsReply struct {
+	Turn  int
+	Start int
+	Rows  [][]byte
+}
+
+//Identifies a halo row by the turn it is from and where it came from
+type haloKey struct {
+	turn      int
+	fromAbove bool
+}
+
+// Worker simulates a band of the world for a broker. Its exported methods are called over net/rpc.
+type Worker struct {
+	//mu guards the band and is held for the whole of Step
+	mu           sync.Mutex
+	band         *gol.Band
+	turn         int
+	above, below *rpc.Client
+	//loaded is the epoch the band was handed out in
+	loaded int
+
+	//halosMu guards the rest, which neighbours and Load need while Step runs
+	halosMu sync.Mutex
+	epoch   int
+	halos   map[haloKey]chan []byte
+	//abort is closed to stop Step when the world is handed out again
+	abort chan bool
+}
+
+// NewWorker returns a worker with nothing to simulate until it is loaded.
+func NewWorker() *Worker {
+	return &Worker{halos: make(map[haloKey]chan []byte), abort: make(chan bool)}
+}
+
+// Serve answers calls to a new Worker from listener until it is closed.
+func Serve(listener net.Listener) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Worker", NewWorker()); err != nil {
+		return err
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+//Returns the channel the halo for key is delivered on, making it if it doesn't exist yet
+func (w *Worker) slot(key haloKey) chan []byte {
+	w.halosMu.Lock()
+	defer w.halosMu.Unlock()
+	c, ok := w.halos[key]
+	if !ok {
+		c = make(chan []byte, 1)
+		w.halos[key] = c
+	}
+	return c
+}
+
+//Waits for the halo for key, which may already have arrived, giving up if abort is closed
+func (w *Worker) await(key haloKey, abort <-chan bool) ([]byte, error) {
+	select {
+	case row := <-w.slot(key):
+		w.halosMu.Lock()
+		delete(w.halos, key)
+		w.halosMu.Unlock()
+		return row, nil
+	case <-abort:
+		return nil, errAborted
+	case <-time.After(HaloTimeout):
+		return nil, errors.New("timed out waiting for the halo from turn " + strconv.Itoa(key.turn))
+	}
+}
+
+//Returned by Step when it is stopped by Load
+var errAborted = errors.New("the world was handed out again")
+
+// Load replaces whatever the worker was simulating with the band in args,
+// connecting to the workers above and below it.
+func (w *Worker) Load(args LoadArgs, reply *bool) error {
+	p := gol.Params{Turns: args.Turn, ImageWidth: args.Width, ImageHeight: args.Height}
+	for _, value := range []struct {
+		name string
+		set  func(string) error
+	}{
+		{args.Rule, p.Rule.Set},
+		{args.Boundary, p.Boundary.Set},
+		{args.Backend, p.Backend.Set},
+	} {
+		if err := value.set(value.name); err != nil {
+			return err
+		}
+	}
+	band, err := gol.NewBand(p, args.Start, args.Rows)
+	if err != nil {
+		return err
+	}
+	above, err := rpc.Dial("tcp", args.Above)
+	if err != nil {
+		return err
+	}
+	below, err := rpc.Dial("tcp", args.Below)
+	if err != nil {
+		above.Close()
+		return err
+	}
+
+	//Any turns still being run are from before the broker lost track of them, so they are stopped.
+	//abort is replaced as it is closed, so that Loads running at the same time never close it twice
+	w.halosMu.Lock()
+	if args.Epoch < w.epoch {
+		w.halosMu.Unlock()
+		above.Close()
+		below.Close()
+		return errStaleLoad
+	}
+	close(w.abort)
+	w.abort = make(chan bool)
+	if args.Epoch > w.epoch {
+		w.epoch = args.Epoch
+		w.halos = make(map[haloKey]chan []byte)
+	}
+	w.halosMu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	//A Load from a later epoch may have got in while this one waited
+	if args.Epoch < w.loaded {
+		above.Close()
+		below.Close()
+		return errStaleLoad
+	}
+	w.closeNeighbours()
+	w.band, w.turn, w.above, w.below, w.loaded = band, args.Turn, above, below, args.Epoch
+	*reply = true
+	return nil
+}
+
+//Returned by Load when the worker has already been handed the world in a later epoch
+var errStaleLoad = errors.New("the band is from before the world was last handed out")
+
+// Ping lets the broker know that the worker is still there, even while it runs turns.
+func (w *Worker) Ping(args bool, reply *bool) error {
+	*reply = true
+	return nil
+}
+
+//Closes the connections to the neighbours, which mu must be held for
+func (w *Worker) closeNeighbours() {
+	if w.above != nil {
+		w.above.Close()
+		w.below.Close()
+		w.above, w.below = nil, nil
+	}
+}
+
+// Halo takes a row from the neighbour above or below, for the worker to use when it gets to that turn.
+func (w *Worker) Halo(args HaloArgs, reply *bool) error {
+	w.halosMu.Lock()
+	epoch := w.epoch
+	w.halosMu.Unlock()
+	if args.Epoch != epoch {
+		return errors.New("the halo is from before the world was handed out again")
+	}
+	select {
+	case w.slot(haloKey{turn: args.Turn, fromAbove: args.FromAbove}) <- args.Row:
+		*reply = true
+		return nil
+	default:
+		return errors.New("the halo from turn " + strconv.Itoa(args.Turn) + " was sent twice")
+	}
+}
+
+// Step runs args.Turns turns, sending the edges of the band to the neighbours at the start of each
+// and waiting for theirs.
+func (w *Worker) Step(args StepArgs, reply *StepReply) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.band == nil {
+		return errors.New("the worker hasn't been loaded")
+	}
+	w.halosMu.Lock()
+	epoch, abort := w.epoch, w.abort
+	w.halosMu.Unlock()
+	if args.Epoch != epoch || args.Epoch != w.loaded {
+		return errAborted
+	}
+	for i := 0; i < args.Turns; i++ {
+		top, bottom := w.band.Edges()
+		//The top row is the halo below the band above, and the bottom row is the halo above the band below
+		toAbove := w.above.Go("Worker.Halo", HaloArgs{Epoch: epoch, Turn: w.turn, FromAbove: false, Row: top}, new(bool), nil)
+		toBelow := w.below.Go("Worker.Halo", HaloArgs{Epoch: epoch, Turn: w.turn, FromAbove: true, Row: bottom}, new(bool), nil)
+		above, err := w.await(haloKey{turn: w.turn, fromAbove: true}, abort)
+		if err != nil {
+			return err
+		}
+		below, err := w.await(haloKey{turn: w.turn, fromAbove: false}, abort)
+		if err != nil {
+			return err
+		}
+		for _, call := range []*rpc.Call{toAbove, toBelow} {
+			select {
+			case <-call.Done:
+				if call.Error != nil {
+					return call.Error
+				}
+			case <-abort:
+				return errAborted
+			}
+		}
+		w.band.Step(above, below)
+		w.turn++
+	}
+	*reply = StepReply{Turn: w.turn, Alive: w.band.AliveCount()}
+	return nil
+}
+
+// Rows sends back the band as it stands.
+func (w *Worker) Rows(args bool, reply *RowsReply) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.band == nil {
+		return errors.New("the worker hasn't been loaded")
+	}
+	*reply = RowsReply{Turn: w.turn, Start: w.band.Start(), Rows: w.band.Rows()}
+	return nil
+}
@@ -27,18 +27,32 @@ func getKeyboardCommand(key chan<- rune) {
 	}
 }
 
+//termboxOn is set once termbox has been initialised, as closing it otherwise blocks forever
+var termboxOn bool
+
 // startControlServer initialises termbox and prints basic information about the game configuration.
-func startControlServer(p golParams) {
-	e := termbox.Init()
-	check(e)
+// Headless runs print the information without initialising termbox, so no keys are read.
+func startControlServer(p golParams, headless bool) {
+	if !headless {
+		e := termbox.Init()
+		check(e)
+		termboxOn = true
+	}
 
-	fmt.Println("Threads:", p.threads)
-	fmt.Println("Width:", p.imageWidth)
-	fmt.Println("Height:", p.imageHeight)
+	fmt.Println("Threads:", p.Threads)
+	fmt.Println("Width:", p.ImageWidth)
+	fmt.Println("Height:", p.ImageHeight)
+	fmt.Println("Rule:", p.Rule)
+	fmt.Println("Boundary:", p.Boundary)
+	fmt.Println("Backend:", p.Backend)
+	fmt.Println("Engine:", p.Engine)
 }
 
 // StopControlServer closes termbox.
 // If the program is terminated without closing termbox the terminal window may misbehave.
 func StopControlServer() {
-	termbox.Close()
+	if termboxOn {
+		termbox.Close()
+		termboxOn = false
+	}
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+//How long the controller waits for the server to answer
+const controllerTimeout = 10 * time.Second
+
+// controller drives a game run in another process with -http, so that the game carries on without it.
+// It uses the same control API as any other client, so several controllers can be connected at once.
+type controller struct {
+	url    string
+	client *http.Client
+}
+
+// newController returns a controller for the server at address, which is a host and port such as localhost:8080,
+// just a port such as :8080, or a URL.
+func newController(address string) *controller {
+	if !strings.Contains(address, "://") {
+		if strings.HasPrefix(address, ":") {
+			address = "localhost" + address
+		}
+		address = "http://" + address
+	}
+	return &controller{url: strings.TrimSuffix(address, "/"), client: &http.Client{Timeout: controllerTimeout}}
+}
+
+//Makes a request to the control API, returning the status of the game afterwards
+func (c *controller) request(method, path string) (controlStatus, error) {
+	var s controlStatus
+	req, err := http.NewRequest(method, c.url+path, nil)
+	if err != nil {
+		return s, err
+	}
+	response, err := c.client.Do(req)
+	if err != nil {
+		return s, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return s, errors.New("the server answered " + path + " with " + response.Status)
+	}
+	return s, json.NewDecoder(response.Body).Decode(&s)
+}
+
+// status returns the status of the game without changing it.
+func (c *controller) status() (controlStatus, error) {
+	return c.request(http.MethodGet, "/status")
+}
+
+// key carries out a key pressed in the controller, returning what to print and whether the controller should stop.
+// 's' has the server write out the world, 'p' pauses or resumes the game, 'q' disconnects and leaves the game running,
+// and 'k' shuts the server down, writing out the world and any checkpoint as 'q' does when the game runs in the same process.
+// Other keys are ignored.
+func (c *controller) key(key rune) (string, bool, error) {
+	switch key {
+	case 's':
+		s, err := c.request(http.MethodPost, "/snapshot")
+		return "Snapshot of turn " + strconv.Itoa(s.Turn) + " written by the server", false, err
+	case 'p':
+		s, err := c.status()
+		if err != nil {
+			return "", false, err
+		}
+		if s.Paused {
+			_, err = c.request(http.MethodPost, "/resume")
+			return "Continuing", false, err
+		}
+		s, err = c.request(http.MethodPost, "/pause")
+		return "Turn: " + strconv.Itoa(s.Turn) + "\nPaused", false, err
+	case 'q':
+		return "Disconnected, the game carries on without the controller", true, nil
+	case 'k':
+		s, err := c.request(http.MethodPost, "/quit")
+		return "Server shut down at turn " + strconv.Itoa(s.Turn), true, err
+	}
+	return "", false, nil
+}
+
+// runController connects to the game served at address and controls it with keys read from the terminal,
+// printing the number of alive cells every 2 seconds, until it is told to stop or the game finishes.
+// It returns an error if the server can't be reached.
+func runController(address string) error {
+	c := newController(address)
+	s, err := c.status()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Connected at turn", s.Turn, "of", s.Turns)
+	if s.Paused {
+		fmt.Println("Paused")
+	}
+
+	e := termbox.Init()
+	check(e)
+	termboxOn = true
+	defer StopControlServer()
+	keys := make(chan rune, 10)
+	go getKeyboardCommand(keys)
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case key := <-keys:
+			message, stop, err := c.key(key)
+			if err != nil {
+				return err
+			}
+			if message != "" {
+				fmt.Println(message)
+			}
+			if stop {
+				return nil
+			}
+		case <-ticker.C:
+			s, err := c.status()
+			if err != nil {
+				return err
+			}
+			if s.Done {
+				fmt.Println("The game has finished")
+				return nil
+			}
+			if !s.Paused {
+				fmt.Println("Turn:", s.Turn, "Cells alive: ", s.Alive)
+			}
+		}
+	}
+}
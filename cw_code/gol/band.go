@@ -0,0 +1,102 @@
+package gol
+
+import (
+	"errors"
+)
+
+// Band is some whole rows of a world, simulated on their own by engines that spread the world over
+// several processes. Whoever holds the rows just above and below the band hands them to Step every turn.
+type Band struct {
+	p      Params
+	strip  strip
+	start  int
+	height int
+	//The columns of the strip and the cells beyond them, halos included
+	first, last, west, east []byte
+	//Scratch space as wide as the world
+	halo, edge []byte
+	stats      stripStats
+}
+
+// NewBand returns the band of a world run with p whose first row is row start of the world.
+// p.ImageWidth and p.ImageHeight are the size of the whole world, and p.Threads is ignored.
+// NewBand returns an error if p isn't valid or the rows don't fit in the world.
+// CrossSurface can't be used, as every band would need the edges of the whole world.
+func NewBand(p Params, start int, rows [][]byte) (*Band, error) {
+	p.Threads = 1
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.Engine != Workers {
+		return nil, errors.New("bands can only be run by the workers engine")
+	}
+	if p.Boundary == CrossSurface {
+		return nil, errors.New("bands can't be run on a cross-surface")
+	}
+	if p.DetectCycles || p.StopOnCycle || p.FrameEvery > 0 || p.StatsEvery > 0 {
+		return nil, errors.New("bands can't detect cycles or record frames or stats")
+	}
+	if len(rows) < 1 || start < 0 || start+len(rows) > p.ImageHeight {
+		return nil, errors.New("the band doesn't fit in the world")
+	}
+	for _, row := range rows {
+		if len(row) != p.ImageWidth {
+			return nil, errors.New("the band's rows must be as wide as the world")
+		}
+	}
+
+	height := len(rows) + 2
+	b := &Band{
+		p:      p,
+		strip:  newStrip(p, p.ImageWidth, height),
+		start:  start,
+		height: height,
+		first:  make([]byte, height),
+		last:   make([]byte, height),
+		west:   make([]byte, height),
+		east:   make([]byte, height),
+		halo:   make([]byte, p.ImageWidth),
+		edge:   make([]byte, p.ImageWidth),
+	}
+	for y, row := range rows {
+		b.strip.writeRow(y+1, row)
+	}
+	b.stats = scanStats(b.strip, p.ImageWidth, height)
+	return b, nil
+}
+
+// Start returns the row of the world the band starts at.
+func (b *Band) Start() int {
+	return b.start
+}
+
+// Edges returns copies of the first and last rows of the band, which the bands above and below it need.
+func (b *Band) Edges() (top, bottom []byte) {
+	top = make([]byte, b.p.ImageWidth)
+	bottom = make([]byte, b.p.ImageWidth)
+	b.strip.readRow(1, top)
+	b.strip.readRow(b.height-2, bottom)
+	return top, bottom
+}
+
+// Step works out the next generation of the band, given the rows just above and below it.
+// Beyond the top and bottom of the world these are the rows from the other side of it, as if it were a torus,
+// and the boundary decides what is made of them.
+func (b *Band) Step(above, below []byte) {
+	b.strip.writeRow(0, above)
+	b.strip.writeRow(b.height-1, below)
+	fixStripHalos(b.strip, b.p.Boundary, b.start == 0, b.start+b.height-2 == b.p.ImageHeight, b.height, b.halo, b.edge)
+	b.strip.columns(b.first, b.last)
+	b.p.Boundary.sides(b.first, b.last, b.west, b.east, b.start, b.p.ImageHeight, nil, 0)
+	b.stats = b.strip.step(b.west, b.east)
+}
+
+// Rows returns copies of the rows of the band.
+func (b *Band) Rows() [][]byte {
+	return stripRows(b.strip, b.p.ImageWidth, b.height).rows
+}
+
+// AliveCount returns the number of live cells in the band.
+func (b *Band) AliveCount() int {
+	return b.stats.population
+}
@@ -0,0 +1,200 @@
+package gol
+
+import (
+	"math/bits"
+)
+
+//A strip packing 64 cells into each word, with cell x of a row in bit x%64 of word x/64.
+//Every row has room for one more bit after the last cell, where step puts the cell just beyond the right edge.
+type bitStrip struct {
+	width int
+	rows  [][]uint64
+	next  [][]uint64
+	rule  bitRule
+}
+
+//A Life-like rule worked out for 64 cells at a time
+type bitRule struct {
+	//counts lists the neighbour counts the rule cares about, with which of them cause births and survivals
+	counts   []int
+	birth    uint16
+	survival uint16
+}
+
+func newBitRule(rule Rule) bitRule {
+	rule = rule.orDefault()
+	r := bitRule{birth: rule.birth, survival: rule.survival}
+	for n := 0; n <= 8; n++ {
+		if (rule.birth|rule.survival)&(1<<uint(n)) != 0 {
+			r.counts = append(r.counts, n)
+		}
+	}
+	return r
+}
+
+//Returns the next generation of the 64 cells in row, given the same 64 cells of each of its eight
+//neighbours: every bit of neighbours[i] holds one neighbour of the cell in that position of row
+func (r bitRule) next(row uint64, neighbours *[8]uint64) uint64 {
+	//Each bit of s0 to s3 is one bit of the number of alive neighbours of the cell in that position
+	var s0, s1, s2, s3 uint64
+	for _, v := range neighbours {
+		s0, s1, s2, s3 = addBit(s0, s1, s2, s3, v)
+	}
+
+	var born, stays uint64
+	for _, n := range r.counts {
+		match := equalBit(s0, n&1) & equalBit(s1, n>>1&1) & equalBit(s2, n>>2&1) & equalBit(s3, n>>3&1)
+		if r.birth&(1<<uint(n)) != 0 {
+			born |= match
+		}
+		if r.survival&(1<<uint(n)) != 0 {
+			stays |= match
+		}
+	}
+	return (^row & born) | (row & stays)
+}
+
+func newBitStrip(rule Rule, width int, height int) *bitStrip {
+	s := &bitStrip{width: width, rule: newBitRule(rule)}
+	words := width/64 + 1
+	s.rows = make([][]uint64, height)
+	s.next = make([][]uint64, height)
+	for i := 0; i < height; i++ {
+		s.rows[i] = make([]uint64, words)
+		s.next[i] = make([]uint64, words)
+	}
+	return s
+}
+
+func (s *bitStrip) get(x, y int) byte {
+	if s.rows[y][x/64]&(1<<uint(x%64)) != 0 {
+		return Alive
+	}
+	return 0
+}
+
+func (s *bitStrip) set(x, y int, state byte) {
+	if state == Alive {
+		s.rows[y][x/64] |= 1 << uint(x%64)
+	} else {
+		s.rows[y][x/64] &^= 1 << uint(x%64)
+	}
+}
+
+func (s *bitStrip) readRow(y int, dst []byte) {
+	for x := 0; x < s.width; x++ {
+		dst[x] = s.get(x, y)
+	}
+}
+
+func (s *bitStrip) writeRow(y int, src []byte) {
+	row := s.rows[y]
+	for i := range row {
+		row[i] = 0
+	}
+	for x := 0; x < s.width; x++ {
+		if src[x] == Alive {
+			row[x/64] |= 1 << uint(x%64)
+		}
+	}
+}
+
+func (s *bitStrip) columns(first []byte, last []byte) {
+	for y := range s.rows {
+		first[y], last[y] = s.get(0, y), s.get(s.width-1, y)
+	}
+}
+
+func (s *bitStrip) live() []cellState {
+	var live []cellState
+	for y, row := range s.rows[1 : len(s.rows)-1] {
+		for i, word := range row {
+			for word != 0 {
+				x := i*64 + bits.TrailingZeros64(word)
+				live = append(live, cellState{Cell: Cell{X: x, Y: y}, state: Alive})
+				word &= word - 1
+			}
+		}
+	}
+	return live
+}
+
+func (s *bitStrip) step(west []byte, east []byte) stripStats {
+	words := len(s.rows[0])
+	//The cell just beyond the right edge goes in the spare bit after the last cell
+	eastWord, eastBit := s.width/64, uint64(1)<<uint(s.width%64)
+	for y, row := range s.rows {
+		if east[y] == Alive {
+			row[eastWord] |= eastBit
+		} else {
+			row[eastWord] &^= eastBit
+		}
+	}
+	//Only the bits before the spare bit hold cells of the next generation
+	lastMask := eastBit - 1
+
+	var stats stripStats
+	for y := 1; y < len(s.rows)-1; y++ {
+		above, row, below := s.rows[y-1], s.rows[y], s.rows[y+1]
+		westAbove, westRow, westBelow := westBit(west[y-1]), westBit(west[y]), westBit(west[y+1])
+		for i := 0; i < words; i++ {
+			neighbours := [8]uint64{
+				shiftWest(above, i, westAbove), above[i], shiftEast(above, i),
+				shiftWest(row, i, westRow), shiftEast(row, i),
+				shiftWest(below, i, westBelow), below[i], shiftEast(below, i),
+			}
+			s.next[y][i] = s.rule.next(row[i], &neighbours)
+		}
+		s.next[y][eastWord] &= lastMask
+		for i := eastWord + 1; i < words; i++ {
+			s.next[y][i] = 0
+		}
+		stats.addWords(y-1, row, s.next[y], eastWord, lastMask)
+	}
+	s.rows, s.next = s.next, s.rows
+	return stats
+}
+
+//Adds v to the four bit counters s0 to s3, with a carry rippling up from s0 for every bit of v that is set
+func addBit(s0, s1, s2, s3, v uint64) (uint64, uint64, uint64, uint64) {
+	c0 := s0 & v
+	s0 ^= v
+	c1 := s1 & c0
+	s1 ^= c0
+	c2 := s2 & c1
+	s2 ^= c1
+	s3 |= c2
+	return s0, s1, s2, s3
+}
+
+func westBit(cell byte) uint64 {
+	if cell == Alive {
+		return 1
+	}
+	return 0
+}
+
+//Returns word i of row with every cell moved one place east, so each bit holds its west neighbour
+func shiftWest(row []uint64, i int, west uint64) uint64 {
+	if i > 0 {
+		west = row[i-1] >> 63
+	}
+	return row[i]<<1 | west
+}
+
+//Returns word i of row with every cell moved one place west, so each bit holds its east neighbour
+func shiftEast(row []uint64, i int) uint64 {
+	var east uint64
+	if i+1 < len(row) {
+		east = row[i+1] << 63
+	}
+	return row[i]>>1 | east
+}
+
+//Returns the bits of s that are equal to bit, which is 0 or 1
+func equalBit(s uint64, bit int) uint64 {
+	if bit == 1 {
+		return s
+	}
+	return ^s
+}
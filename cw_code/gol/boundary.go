@@ -0,0 +1,180 @@
+package gol
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Boundary decides what lies beyond the edges of the world.
+// The zero Boundary is Torus.
+type Boundary int
+
+const (
+	// Torus wraps each edge around to the opposite edge.
+	Torus Boundary = iota
+	// DeadEdges surrounds the world with cells that are always dead.
+	DeadEdges
+	// KleinBottle wraps the left and right edges like a torus,
+	// but wraps the top and bottom edges with the world flipped left to right.
+	KleinBottle
+	// CrossSurface wraps both pairs of edges with the world flipped, making a real projective plane.
+	CrossSurface
+	// Mirror reflects the cells along each edge back into the world.
+	Mirror
+)
+
+var boundaryNames = []string{
+	Torus:        "torus",
+	DeadEdges:    "dead",
+	KleinBottle:  "klein",
+	CrossSurface: "cross",
+	Mirror:       "mirror",
+}
+
+// ParseBoundary returns the boundary with the given name: torus, dead, klein, cross or mirror.
+func ParseBoundary(s string) (Boundary, error) {
+	for b, name := range boundaryNames {
+		if s == name {
+			return Boundary(b), nil
+		}
+	}
+	return Torus, errors.New("unknown boundary " + strconv.Quote(s) + ", expected torus, dead, klein, cross or mirror")
+}
+
+// String returns the name of the boundary.
+func (b Boundary) String() string {
+	if b < 0 || int(b) >= len(boundaryNames) {
+		return "Boundary(" + strconv.Itoa(int(b)) + ")"
+	}
+	return boundaryNames[b]
+}
+
+// Set parses s into b so that a Boundary can be used as a command line flag.
+func (b *Boundary) Set(s string) error {
+	parsed, err := ParseBoundary(s)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+// resolve maps a coordinate up to one cell outside of a width x height world onto the cell it stands for.
+// It returns false if the coordinate is a dead cell outside the world.
+// The top and bottom edges are dealt with before the left and right.
+func (b Boundary) resolve(x, y, width, height int) (int, int, bool) {
+	if y < 0 || y >= height {
+		switch b {
+		case DeadEdges:
+			return 0, 0, false
+		case Mirror:
+			y = clamp(y, height)
+		case KleinBottle, CrossSurface:
+			x = width - 1 - x
+			y = gety(y, height)
+		default:
+			y = gety(y, height)
+		}
+	}
+	if x < 0 || x >= width {
+		switch b {
+		case DeadEdges:
+			return 0, 0, false
+		case Mirror:
+			x = clamp(x, width)
+		case CrossSurface:
+			x = getx(x, width)
+			y = height - 1 - y
+		default:
+			x = getx(x, width)
+		}
+	}
+	return x, y, true
+}
+
+//Brings a coordinate one cell outside of the world back onto the edge
+func clamp(i int, size int) int {
+	if i < 0 {
+		return 0
+	} else if i >= size {
+		return size - 1
+	}
+	return i
+}
+
+//Fixes up a halo row beyond the top or bottom of the world, which is received from the
+//other side of the world as if it were a torus. edge is the row of the world next to the halo
+func (b Boundary) fixHalo(halo []byte, edge []byte) {
+	switch b {
+	case DeadEdges:
+		for i := range halo {
+			halo[i] = 0
+		}
+	case Mirror:
+		copy(halo, edge)
+	case KleinBottle, CrossSurface:
+		for i, j := 0, len(halo)-1; i < j; i, j = i+1, j-1 {
+			halo[i], halo[j] = halo[j], halo[i]
+		}
+	}
+}
+
+//The first and last column of every row of the world, kept twice over so that workers can write
+//the columns for the next turn while others are still reading the columns for this one
+type edgeColumns struct {
+	first [2][]byte
+	last  [2][]byte
+	width int
+}
+
+//Returns the edges of world, which is the world at the start of turn
+func newEdgeColumns(world [][]byte, turn int) *edgeColumns {
+	var edges edgeColumns
+	for i := range edges.first {
+		edges.first[i] = make([]byte, len(world))
+		edges.last[i] = make([]byte, len(world))
+	}
+	for y, row := range world {
+		edges.first[turn%2][y], edges.last[turn%2][y] = row[0], row[len(row)-1]
+	}
+	edges.width = len(world[0])
+	return &edges
+}
+
+//Records the first and last columns of rows of the world from start onwards for the given turn
+func (e *edgeColumns) store(turn int, first []byte, last []byte, start int) {
+	copy(e.first[turn%2][start:], first)
+	copy(e.last[turn%2][start:], last)
+}
+
+//Fills in west and east with the cells just beyond the left and right of each row of a worker's slice,
+//given the first and last column of each row, halos included.
+//start is the row of the world after the top halo and height is the height of the whole world.
+//Only CrossSurface needs edges, which must hold the columns for the current turn.
+func (b Boundary) sides(first []byte, last []byte, west []byte, east []byte, start int, height int, edges *edgeColumns, turn int) {
+	for y := range first {
+		switch b {
+		case DeadEdges:
+			west[y], east[y] = 0, 0
+		case Mirror:
+			west[y], east[y] = first[y], last[y]
+		case CrossSurface:
+			west[y] = edges.at(b, -1, start+y-1, height, turn)
+			east[y] = edges.at(b, edges.width, start+y-1, height, turn)
+		default:
+			west[y], east[y] = last[y], first[y]
+		}
+	}
+}
+
+//Looks up the cell that x, y stands for, which must lie in the first or last column of the world
+func (e *edgeColumns) at(b Boundary, x, y, height, turn int) byte {
+	x, y, ok := b.resolve(x, y, e.width, height)
+	if !ok {
+		return 0
+	}
+	if x == 0 {
+		return e.first[turn%2][y]
+	}
+	return e.last[turn%2][y]
+}
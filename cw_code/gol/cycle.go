@@ -0,0 +1,297 @@
+package gol
+
+import (
+	"strconv"
+)
+
+// Cycle describes how the world was found to repeat itself, see Params.DetectCycles.
+type Cycle struct {
+	//Turn is the first turn the world was the same as at an earlier turn, or moved from it.
+	Turn int
+	//Period is the number of turns between repeats. It is 0 if the world never repeated,
+	//and 1 for still lifes and worlds that have died out.
+	Period int
+	//DX and DY are how far the world moves each period, which is nowhere for oscillators.
+	DX, DY int
+	//Population is the number of live cells in each repeat.
+	Population int
+}
+
+// Found reports whether the world repeated.
+func (c Cycle) Found() bool {
+	return c.Period > 0
+}
+
+// String describes the cycle, such as "period 4 spaceship moving 1,1 from turn 12".
+func (c Cycle) String() string {
+	from := " from turn " + strconv.Itoa(c.Turn)
+	switch {
+	case !c.Found():
+		return "no cycle"
+	case c.Population == 0:
+		return "died out" + from
+	case c.DX != 0 || c.DY != 0:
+		return "period " + strconv.Itoa(c.Period) + " spaceship moving " + strconv.Itoa(c.DX) + "," + strconv.Itoa(c.DY) + from
+	case c.Period == 1:
+		return "still life" + from
+	}
+	return "period " + strconv.Itoa(c.Period) + " oscillator" + from
+}
+
+//The multipliers of the hash for each column and row, which are odd so that they can be undone
+const (
+	hashColumn uint64 = 0x9e3779b97f4a7c15
+	hashRow    uint64 = 0xc2b2ae3d27d4eb4f
+	hashState  uint64 = 0x165667b19e3779f9
+)
+
+//A summary of the live cells in part of the world, which can be added up over the parts of the world
+//and turned into a hash that doesn't change when the world moves.
+//The hash is the sum of hashState * state * hashColumn^x * hashRow^y over every cell that isn't dead,
+//so moving the world right by one multiplies it by hashColumn, which can be undone.
+type fingerprint struct {
+	sum        uint64
+	live       int
+	population int
+	//The bounding box of the cells that aren't dead, which is only set if live isn't 0
+	minX, minY, maxX, maxY int
+	//cells are the cells that aren't dead, which are only sent when the detector asks for them
+	cells []cellState
+}
+
+//Adds in the fingerprint of another part of the world
+func (f *fingerprint) add(other fingerprint) {
+	if other.live == 0 {
+		return
+	}
+	if f.live == 0 {
+		f.minX, f.minY, f.maxX, f.maxY = other.minX, other.minY, other.maxX, other.maxY
+	} else {
+		f.minX, f.minY = min(f.minX, other.minX), min(f.minY, other.minY)
+		f.maxX, f.maxY = max(f.maxX, other.maxX), max(f.maxY, other.maxY)
+	}
+	f.sum += other.sum
+	f.cells = append(f.cells, other.cells...)
+	f.live += other.live
+	f.population += other.population
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+//The powers of the hash multipliers for every column and row of the world, and of their inverses
+type hashPowers struct {
+	columns, rows               []uint64
+	inverseColumns, inverseRows []uint64
+}
+
+func newHashPowers(width, height int) *hashPowers {
+	powers := func(base uint64, n int) []uint64 {
+		p := make([]uint64, n)
+		p[0] = 1
+		for i := 1; i < n; i++ {
+			p[i] = p[i-1] * base
+		}
+		return p
+	}
+	return &hashPowers{
+		columns:        powers(hashColumn, width),
+		rows:           powers(hashRow, height),
+		inverseColumns: powers(inverse(hashColumn), width),
+		inverseRows:    powers(inverse(hashRow), height),
+	}
+}
+
+//Returns the number that a, which must be odd, multiplies with to give 1 as a uint64
+func inverse(a uint64) uint64 {
+	//Each step of Newton's method doubles the number of bits that are right, starting from 3
+	x := a
+	for i := 0; i < 5; i++ {
+		x *= 2 - a*x
+	}
+	return x
+}
+
+//Returns the fingerprint of the rows of a worker's strip apart from its halos, the first of which is row start of the world.
+//row is somewhere to read each row into.
+func stripFingerprint(worldslice strip, powers *hashPowers, start, height int, row []byte) fingerprint {
+	var f fingerprint
+	for y := 1; y < height-1; y++ {
+		worldslice.readRow(y, row)
+		var sum uint64
+		rowFingerprint := fingerprint{minX: -1, minY: start + y - 1, maxY: start + y - 1}
+		for x, state := range row {
+			if state == 0 {
+				continue
+			}
+			sum += hashState * uint64(state) * powers.columns[x]
+			if rowFingerprint.minX < 0 {
+				rowFingerprint.minX = x
+			}
+			rowFingerprint.maxX = x
+			rowFingerprint.live++
+			if state == Alive {
+				rowFingerprint.population++
+			}
+		}
+		rowFingerprint.sum = sum * powers.rows[start+y-1]
+		f.add(rowFingerprint)
+	}
+	return f
+}
+
+//What a detector tells each worker at the start of every turn it is sent a fingerprint for
+type verdict struct {
+	//end is the turn the game now finishes at
+	end int
+	//detecting is false once fingerprints are no longer needed
+	detecting bool
+	//capture asks for the cells that aren't dead along with the fingerprint of the next turn
+	capture bool
+}
+
+//What a detector found, and how far the final world has to be moved to make up for the turns that were skipped
+type detected struct {
+	cycle          Cycle
+	shiftX, shiftY int
+}
+
+//Where and when a world was first seen, keyed by its hash with the move undone
+type sighting struct {
+	turn, x, y int
+}
+
+type sightingKey struct {
+	hash          uint64
+	live          int
+	width, height int
+}
+
+//The longest period looked for, so that the worlds seen don't pile up over a long game
+const maxCyclePeriod = 1 << 16
+
+//Adds up the fingerprints the workers send at the start of every turn and looks for a world that has been seen before.
+//As hashes can collide, a world that seems to repeat is captured on the next turn and again a period later,
+//and only counts as a cycle if the two really are the same, otherwise the search carries on.
+//Once a cycle is found, p.StopOnCycle finishes the game as soon as the final world is known: straight away for
+//oscillators, which can be skipped ahead by whole periods, or after moving it for spaceships on a torus.
+//Spaceships elsewhere may come up against the edges of the world, so they are only reported.
+func detectCycles(p Params, powers *hashPowers, fingerprints <-chan fingerprint, verdicts []chan verdict, found chan<- detected) {
+	seen := make(map[sightingKey]sighting)
+	//order holds the keys of seen from the earliest sighting on, so that sightings too long ago can be forgotten
+	var order []sightingKey
+	var result detected
+	end := p.Turns
+	detecting := true
+	//While confirming candidate, the world captured at captureAt is compared with the world at confirmAt
+	confirming := false
+	var candidate Cycle
+	captureAt, confirmAt := 0, 0
+	var captured []cellState
+	for turn := p.first; turn < end; turn++ {
+		var f fingerprint
+		for range verdicts {
+			f.add(<-fingerprints)
+		}
+
+		switch {
+		case confirming && turn == captureAt:
+			captured = f.cells
+			confirmAt = turn + candidate.Period
+		case confirming && turn == confirmAt:
+			c := candidate
+			if repeats(captured, f.cells, c.DX, c.DY, p.ImageWidth, p.ImageHeight) {
+				result.cycle = c
+				detecting = false
+				moving := c.DX != 0 || c.DY != 0
+				if p.StopOnCycle && (!moving || p.Boundary == Torus) {
+					//The remaining turns are made up of whole periods and a few turns left over,
+					//which are still simulated so that the world ends up in the right phase
+					periods, left := (p.Turns-turn)/c.Period, (p.Turns-turn)%c.Period
+					end = turn + left
+					result.shiftX, result.shiftY = periods*c.DX, periods*c.DY
+				}
+			} else {
+				//The hashes collided, or a spaceship ran into an edge, so the world hasn't really repeated
+				confirming, captured = false, nil
+			}
+		case !confirming:
+			for len(order) > 0 && turn-seen[order[0]].turn > maxCyclePeriod {
+				delete(seen, order[0])
+				order = order[1:]
+			}
+			key := sightingKey{hash: f.sum, live: f.live}
+			if f.live > 0 {
+				key.hash *= powers.inverseColumns[f.minX] * powers.inverseRows[f.minY]
+				key.width, key.height = f.maxX-f.minX+1, f.maxY-f.minY+1
+			}
+			if first, ok := seen[key]; ok {
+				candidate = Cycle{Turn: turn, Period: turn - first.turn, DX: f.minX - first.x, DY: f.minY - first.y, Population: f.population}
+				if f.live == 0 {
+					candidate.DX, candidate.DY = 0, 0
+				}
+				confirming, captureAt = true, turn+1
+			} else {
+				seen[key] = sighting{turn: turn, x: f.minX, y: f.minY}
+				order = append(order, key)
+			}
+		}
+
+		capture := confirming && (turn+1 == captureAt || turn+1 == confirmAt)
+		for _, v := range verdicts {
+			v <- verdict{end: end, detecting: detecting, capture: capture}
+		}
+		if !detecting {
+			break
+		}
+	}
+	found <- result
+}
+
+//Reports whether after is the cells of before moved by dx, dy, wrapping around the edges of the world
+func repeats(before, after []cellState, dx, dy, width, height int) bool {
+	if len(before) != len(after) {
+		return false
+	}
+	cells := make(map[cellState]bool, len(after))
+	for _, c := range after {
+		cells[c] = true
+	}
+	for _, c := range before {
+		c.X, c.Y = wrap(c.X+dx, width), wrap(c.Y+dy, height)
+		if !cells[c] {
+			return false
+		}
+	}
+	return true
+}
+
+//Returns i wrapped around to lie between 0 and size
+func wrap(i, size int) int {
+	i %= size
+	if i < 0 {
+		i += size
+	}
+	return i
+}
+
+//Returns world moved by dx, dy, wrapping around its edges
+func shifted(world *World, dx, dy int) *World {
+	moved := NewWorld(world.width, world.height)
+	for y, row := range world.cells {
+		copy(moved.cells[wrap(y+dy, world.height)], row[wrap(-dx, world.width):])
+		copy(moved.cells[wrap(y+dy, world.height)][world.width-wrap(-dx, world.width):], row)
+	}
+	return moved
+}
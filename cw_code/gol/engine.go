@@ -0,0 +1,128 @@
+package gol
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Engine decides how the turns of a game are worked out.
+// The zero Engine is Workers.
+type Engine int
+
+const (
+	// Workers splits the world into strips simulated by p.Threads worker goroutines.
+	Workers Engine = iota
+	// HashLife memoises the futures of repeated parts of the world in a quadtree,
+	// so it can jump ahead by huge numbers of turns at once.
+	// It simulates the world on an unbounded plane, ignoring Boundary and Threads,
+	// and only supports Life-like rules with two states in which empty space stays empty.
+	HashLife
+	// Sparse stores only the 64x64 tiles of an unbounded plane that have live cells in them,
+	// so patterns can grow without limit. Like HashLife, it ignores Boundary and Threads
+	// and only supports Life-like rules with two states in which empty space stays empty.
+	Sparse
+)
+
+var engineNames = []string{
+	Workers:  "workers",
+	HashLife: "hashlife",
+	Sparse:   "sparse",
+}
+
+// ParseEngine returns the engine with the given name: workers, hashlife or sparse.
+func ParseEngine(s string) (Engine, error) {
+	for e, name := range engineNames {
+		if s == name {
+			return Engine(e), nil
+		}
+	}
+	return Workers, errors.New("unknown engine " + strconv.Quote(s) + ", expected workers, hashlife or sparse")
+}
+
+// String returns the name of the engine.
+func (e Engine) String() string {
+	if e < 0 || int(e) >= len(engineNames) {
+		return "Engine(" + strconv.Itoa(int(e)) + ")"
+	}
+	return engineNames[e]
+}
+
+// Set parses s into e so that an Engine can be used as a command line flag.
+func (e *Engine) Set(s string) error {
+	parsed, err := ParseEngine(s)
+	if err != nil {
+		return err
+	}
+	*e = parsed
+	return nil
+}
+
+// Unbounded reports whether the engine simulates an infinite plane rather than the world it was given,
+// so that live cells can end up outside of the world.
+func (e Engine) Unbounded() bool {
+	return e != Workers
+}
+
+//What an engine hands back once it has simulated every turn
+type outcome struct {
+	//world is the final world, cropped to the size of the world the game started with
+	world *World
+	//alive is every live cell, which for unbounded engines may lie outside of world
+	alive []Cell
+	//cycle is how the world was found to repeat, if it was looked for
+	cycle Cycle
+}
+
+//Answers whatever Game has asked for between the turns of an engine that runs on a single goroutine,
+//in the same way threadSyncer and a lone worker would, and records the frame for turn if it is due.
+//live returns the cells that aren't dead.
+func serveRequests(p Params, s syncChans, k keyChans, turn int, live func() []cellState) {
+	select {
+	case <-s.periodicOutput:
+		s.periodicNumber <- count{turn: turn, alive: aliveIn(live())}
+	case <-k.startSend:
+		k.snapshots <- worldPart{turn: turn, cells: live()}
+	case <-k.printTurns:
+		k.pause.Add(1)
+		cells := live()
+		k.turnsPrinted <- worldPart{turn: turn, cells: cells, alive: aliveIn(cells)}
+	default:
+	}
+	k.pause.Wait()
+	if recording(p, turn) {
+		k.frames <- framePart{turn: turn, cells: live()}
+	}
+}
+
+//Returns the number of cells that are alive rather than dying
+func aliveIn(cells []cellState) int {
+	number := 0
+	for _, c := range cells {
+		if c.state == Alive {
+			number++
+		}
+	}
+	return number
+}
+
+//Returns the cells that lie outside of world
+func outside(cells []Cell, world *World) []Cell {
+	var out []Cell
+	for _, c := range cells {
+		if c.X < 0 || c.X >= world.width || c.Y < 0 || c.Y >= world.height {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+//Builds the part of an unbounded engine's plane that the game started with
+func cropped(alive []Cell, width int, height int) outcome {
+	world := NewWorld(width, height)
+	for _, c := range alive {
+		if c.X >= 0 && c.X < width && c.Y >= 0 && c.Y < height {
+			world.cells[c.Y][c.X] = Alive
+		}
+	}
+	return outcome{world: world, alive: alive}
+}
@@ -0,0 +1,55 @@
+package gol
+
+// Frame is the world as it was at the start of a turn, recorded for Game.Frames.
+type Frame struct {
+	Turn  int
+	World *World
+}
+
+//The live cells of one worker's part of the world at the start of a turn that is being recorded,
+//with their coordinates in the whole world
+type framePart struct {
+	turn  int
+	cells []cellState
+}
+
+//Reports whether the world at the start of turn should be recorded
+func recording(p Params, turn int) bool {
+	return p.FrameEvery > 0 && turn%p.FrameEvery == 0
+}
+
+// Frames returns a channel that receives the world every p.FrameEvery turns, starting with the world
+// the game started with and ending with the final world if p.Turns is a multiple of p.FrameEvery.
+// The channel is closed once the game has finished. The workers wait for each frame to be received,
+// so the channel must be read until it is closed. Without p.FrameEvery the channel is closed straight away.
+func (g *Game) Frames() <-chan Frame {
+	return g.frames
+}
+
+//Puts together the parts of each frame sent by the workers and hands the frames on to Frames
+func (g *Game) collectFrames() {
+	defer close(g.frames)
+	p := g.params
+	if p.FrameEvery <= 0 {
+		return
+	}
+	//A resumed game records from the first multiple of FrameEvery it reaches
+	first := (p.first + p.FrameEvery - 1) / p.FrameEvery * p.FrameEvery
+	for turn := first; turn < p.Turns; turn += p.FrameEvery {
+		world := NewWorld(p.ImageWidth, p.ImageHeight)
+		for i := 0; i < p.Threads; i++ {
+			part := <-g.keys.frames
+			for _, c := range part.cells {
+				//Unbounded engines send cells that may have left the world
+				if c.X >= 0 && c.X < world.width && c.Y >= 0 && c.Y < world.height {
+					world.cells[c.Y][c.X] = c.state
+				}
+			}
+		}
+		g.frames <- Frame{Turn: turn, World: world}
+	}
+	<-g.done
+	if recording(p, p.Turns) {
+		g.frames <- Frame{Turn: p.Turns, World: g.final.world}
+	}
+}
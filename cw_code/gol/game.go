@@ -0,0 +1,402 @@
+// Package gol is a concurrent Game of Life engine.
+// A World is split into horizontal strips which are each simulated by a worker goroutine,
+// with the rows on the edge of each strip exchanged between neighbouring workers every turn,
+// or read straight from a world they all share, see Params.Shared.
+// For very long runs, or patterns that grow without limit, an unbounded engine can be used instead, see Engine.
+package gol
+
+import (
+	"errors"
+	"sync"
+)
+
+// Params provides the details of how to run the Game of Life.
+type Params struct {
+	Turns       int
+	Threads     int
+	ImageWidth  int
+	ImageHeight int
+	Rule        Rule
+	Boundary    Boundary
+	Backend     Backend
+	Engine      Engine
+	//MaxNodes caps the number of nodes HashLife keeps between garbage collections, 0 means DefaultMaxNodes
+	MaxNodes int
+	//FrameEvery is how many turns apart the frames sent to Game.Frames are, 0 records no frames
+	FrameEvery int
+	//DetectCycles looks for the world repeating itself, which Game.Cycle reports.
+	//A repeat is only reported once it has been checked cell by cell a period later,
+	//so one that comes too near the end of the game isn't. Periods of up to 65536 turns are looked for.
+	//StopOnCycle also finishes the game as soon as the final world is known, which implies DetectCycles.
+	DetectCycles bool
+	StopOnCycle  bool
+	//StatsEvery is how many turns apart the stats sent to Game.Stats are, 0 records no stats
+	StatsEvery int
+	//Shared has the workers step their rows of a single world held in shared memory, waiting at a barrier each turn,
+	//instead of each keeping a strip of their own and swapping the rows on its edges over channels
+	Shared bool
+
+	//first is the turn the game starts from, which is only set by Resume
+	first int
+}
+
+// Validate returns an error if the game described by p can't be run.
+func (p Params) Validate() error {
+	if p.ImageWidth < 1 || p.ImageHeight < 1 {
+		return errors.New("the world must be at least 1x1")
+	}
+	if p.FrameEvery < 0 {
+		return errors.New("frames can't be a negative number of turns apart")
+	}
+	if p.StatsEvery < 0 {
+		return errors.New("stats can't be a negative number of turns apart")
+	}
+	if p.StatsEvery > 0 && p.Engine != Workers {
+		return errors.New("stats can only be recorded by the workers engine")
+	}
+	if (p.DetectCycles || p.StopOnCycle) && p.Engine != Workers {
+		return errors.New("cycles can only be detected by the workers engine")
+	}
+	if p.Shared && p.Engine != Workers {
+		return errors.New("only the workers engine can share the world between workers")
+	}
+	if p.StopOnCycle && p.FrameEvery > 0 {
+		return errors.New("frames can't be recorded by a game that may stop early")
+	}
+	if p.Engine.Unbounded() {
+		if p.Rule.States() > 2 {
+			return errors.New("the " + p.Engine.String() + " engine doesn't support Generations rules")
+		}
+		if p.Rule.Born(0) {
+			return errors.New("the " + p.Engine.String() + " engine doesn't support rules with B0")
+		}
+		return nil
+	}
+	if p.Threads < 1 {
+		return errors.New("there must be at least one thread")
+	}
+	if p.Threads > p.ImageHeight {
+		return errors.New("there can't be more threads than rows in the world")
+	}
+	if p.Backend == BitBackend && p.Rule.States() > 2 {
+		return errors.New("the bits backend doesn't support Generations rules")
+	}
+	return nil
+}
+
+//Defines channels that Game uses to ask the workers for the current state of the world
+type keyChans struct {
+	startSend    chan bool
+	snapshots    chan worldPart
+	turnsPrinted chan worldPart
+	printTurns   chan bool
+	pause        *sync.WaitGroup
+	frames       chan framePart
+	stats        chan statsPart
+}
+
+//Defines channels that the workers use to stay on the same turn as each other
+type syncChans struct {
+	periodicOutput chan bool
+	periodicNumber chan count
+	threadsyncin   chan bool
+	threadsyncout  chan byte
+}
+
+// Game is a simulation running in the background, started by Start.
+// Its methods may be called from any goroutine while the game runs.
+type Game struct {
+	params Params
+	sync   syncChans
+	keys   keyChans
+
+	//control serialises pause, resume and requests for the world, which are answered from pausedAt while paused
+	control  sync.Mutex
+	paused   bool
+	pausedAt []worldPart
+
+	done   chan bool
+	final  outcome
+	frames chan Frame
+	stats  chan Stats
+}
+
+// State is a game as it stands at the start of a turn, which is enough to carry it on from there.
+type State struct {
+	Turn  int
+	World *World
+	//Outside holds the live cells of unbounded engines that have left the world
+	Outside []Cell
+}
+
+// Alive returns every live cell, including those outside of the world.
+func (s State) Alive() []Cell {
+	return append(s.World.Alive(), s.Outside...)
+}
+
+// Start begins simulating p.Turns turns of world and returns straight away.
+// The world's dimensions take precedence over p.ImageWidth and p.ImageHeight.
+// Start panics if p isn't valid, see Params.Validate.
+func Start(p Params, world *World) *Game {
+	return Resume(p, State{World: world})
+}
+
+// Resume carries on a game from state, which is usually a State saved by an earlier game,
+// simulating the turns from state.Turn up to p.Turns. Turns are numbered from state.Turn onwards.
+// The Workers engine ignores state.Outside.
+// Resume panics if p isn't valid or state.Turn isn't between 0 and p.Turns.
+func Resume(p Params, state State) *Game {
+	world := state.World
+	p.ImageWidth = world.Width()
+	p.ImageHeight = world.Height()
+	if err := p.Validate(); err != nil {
+		panic(err)
+	}
+	if state.Turn < 0 || state.Turn > p.Turns {
+		panic(errors.New("the game can't be resumed from a turn outside of 0 to p.Turns"))
+	}
+	p.first = state.Turn
+	if p.Engine.Unbounded() {
+		//Unbounded engines answer requests on their own goroutine, as if they were a single worker
+		p.Threads = 1
+	}
+
+	g := &Game{params: p, done: make(chan bool), frames: make(chan Frame), stats: make(chan Stats)}
+
+	g.sync.periodicOutput = make(chan bool, p.Threads)
+	g.sync.periodicNumber = make(chan count, p.Threads*p.Threads*p.Threads)
+	g.sync.threadsyncin = make(chan bool, p.Threads)
+	g.sync.threadsyncout = make(chan byte, p.Threads)
+
+	g.keys.startSend = make(chan bool)
+	g.keys.snapshots = make(chan worldPart, p.Threads)
+	g.keys.printTurns = make(chan bool)
+	g.keys.turnsPrinted = make(chan worldPart, p.Threads)
+	g.keys.pause = &sync.WaitGroup{}
+	g.keys.frames = make(chan framePart, p.Threads)
+	g.keys.stats = make(chan statsPart, p.Threads)
+
+	result := make(chan outcome)
+	switch p.Engine {
+	case HashLife:
+		go hashLifeEngine(p, state, g.sync, result, g.keys)
+	case Sparse:
+		go sparseEngine(p, state, g.sync, result, g.keys)
+	default:
+		go distributor(p, world.cells, g.sync, result, g.keys)
+	}
+	go func() {
+		g.final = <-result
+		close(g.done)
+	}()
+	go g.collectFrames()
+	go g.collectStats()
+	return g
+}
+
+// Run simulates p.Turns turns of world and returns the cells that are alive at the end.
+func Run(p Params, world *World) []Cell {
+	return Start(p, world).Wait()
+}
+
+// Wait blocks until every turn has been simulated and returns the cells that are alive at the end.
+// With the HashLife engine this includes cells that have left the world.
+func (g *Game) Wait() []Cell {
+	<-g.done
+	return g.final.alive
+}
+
+// Result blocks until every turn has been simulated and returns the final world.
+func (g *Game) Result() *World {
+	<-g.done
+	return g.final.world
+}
+
+// Cycle blocks until the game has finished and returns how the world was found to repeat itself,
+// which is only looked for if p.DetectCycles or p.StopOnCycle is set.
+func (g *Game) Cycle() Cycle {
+	<-g.done
+	return g.final.cycle
+}
+
+// Done is closed once the game has finished.
+func (g *Game) Done() <-chan bool {
+	return g.done
+}
+
+// AliveCount returns the number of cells alive at the start of the next turn.
+func (g *Game) AliveCount() int {
+	_, alive := g.Count()
+	return alive
+}
+
+// Count returns the next turn along with the number of cells alive at the start of it,
+// or p.Turns and the number alive at the end once every turn has been simulated.
+func (g *Game) Count() (turn, alive int) {
+	g.control.Lock()
+	defer g.control.Unlock()
+	if g.paused {
+		for _, part := range g.pausedAt {
+			turn = part.turn
+			alive += part.alive
+		}
+		return turn, alive
+	}
+	//A finished game may have left requests and counts behind in the channels, which would be stale,
+	//so done is looked at before either of them every time
+	if g.finished() {
+		return g.params.Turns, len(g.final.alive)
+	}
+	select {
+	case g.sync.periodicOutput <- true:
+	case <-g.done:
+		return g.params.Turns, len(g.final.alive)
+	}
+	for i := 0; i < g.params.Threads; i++ {
+		if g.finished() {
+			return g.params.Turns, len(g.final.alive)
+		}
+		select {
+		case c := <-g.sync.periodicNumber:
+			turn = c.turn
+			alive += c.alive
+		case <-g.done:
+			return g.params.Turns, len(g.final.alive)
+		}
+	}
+	return turn, alive
+}
+
+//Reports whether every turn has been simulated, without waiting
+func (g *Game) finished() bool {
+	select {
+	case <-g.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Snapshot returns the cells alive at the start of the next turn.
+// With the HashLife engine this includes cells that have left the world.
+func (g *Game) Snapshot() []Cell {
+	return g.State().Alive()
+}
+
+// SnapshotWorld returns a copy of the world at the start of the next turn.
+func (g *Game) SnapshotWorld() *World {
+	return g.State().World
+}
+
+// State returns the game as it stands at the start of the next turn,
+// or as it ended if every turn has been simulated.
+func (g *Game) State() State {
+	g.control.Lock()
+	defer g.control.Unlock()
+	var parts []worldPart
+	ok := true
+	if g.paused {
+		//The parts are kept for as long as the game is paused, so the world is given its own rows
+		for _, part := range g.pausedAt {
+			rows := make([][]byte, len(part.rows))
+			for y, row := range part.rows {
+				rows[y] = append([]byte(nil), row...)
+			}
+			part.rows = rows
+			parts = append(parts, part)
+		}
+	} else {
+		parts, ok = g.snapshot()
+	}
+	if !ok {
+		<-g.done
+		return State{Turn: g.params.Turns, World: g.final.world, Outside: outside(g.final.alive, g.final.world)}
+	}
+	state := State{World: NewWorld(g.params.ImageWidth, g.params.ImageHeight)}
+	for _, part := range parts {
+		state.Turn = part.turn
+		state.World.place(part)
+		for _, c := range part.cells {
+			if c.state == Alive {
+				state.Outside = append(state.Outside, c.Cell)
+			}
+		}
+	}
+	state.Outside = outside(state.Outside, state.World)
+	return state
+}
+
+//Asks the workers for their part of the world at the start of the next turn
+//Returns false if the game finished first
+func (g *Game) snapshot() ([]worldPart, bool) {
+	select {
+	case g.keys.startSend <- true:
+	case <-g.done:
+		return nil, false
+	}
+	return g.collateBoard()
+}
+
+//Waits for every worker to send its part of the world after a snapshot has been requested
+//Returns false if the game finished before the workers got to the snapshot
+func (g *Game) collateBoard() ([]worldPart, bool) {
+	parts := make([]worldPart, 0, g.params.Threads)
+	for len(parts) < g.params.Threads {
+		select {
+		case part := <-g.keys.snapshots:
+			parts = append(parts, part)
+		case <-g.done:
+			return nil, false
+		}
+	}
+	return parts, true
+}
+
+// Pause stops the workers at the start of the next turn and returns that turn's number.
+// It returns false if the game was already paused or has finished.
+func (g *Game) Pause() (int, bool) {
+	g.control.Lock()
+	defer g.control.Unlock()
+	if g.paused {
+		return 0, false
+	}
+	select {
+	case g.keys.printTurns <- true:
+	case <-g.done:
+		return g.params.Turns, false
+	}
+	//Every worker hands over its part of the world before it stops
+	parts := make([]worldPart, 0, g.params.Threads)
+	for len(parts) < g.params.Threads {
+		select {
+		case part := <-g.keys.turnsPrinted:
+			parts = append(parts, part)
+		case <-g.done:
+			return g.params.Turns, false
+		}
+	}
+	g.paused = true
+	g.pausedAt = parts
+	return parts[0].turn, true
+}
+
+// Paused reports whether the game is paused.
+func (g *Game) Paused() bool {
+	g.control.Lock()
+	defer g.control.Unlock()
+	return g.paused
+}
+
+// Resume lets the workers continue after Pause.
+// It returns false if the game was not paused.
+func (g *Game) Resume() bool {
+	g.control.Lock()
+	defer g.control.Unlock()
+	if !g.paused {
+		return false
+	}
+	g.keys.pause.Done()
+	g.paused = false
+	g.pausedAt = nil
+	return true
+}
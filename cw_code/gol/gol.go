@@ -0,0 +1,513 @@
+package gol
+
+import (
+	"fmt"
+)
+
+//Defines the channels used for the workers to communicate with each other
+//Each turn a whole row is sent down each of them, which the receiver mustn't change
+type workerExchange struct {
+	rTop <-chan []byte //receiving the top halo, the bottom row of the worker above
+	sTop chan<- []byte //sending the top row to the worker above
+	rBot <-chan []byte //receiving the bottom halo, the top row of the worker below
+	sBot chan<- []byte //sending the bottom row to the worker below
+
+	edges *edgeColumns //the first and last column of the world, only shared for CrossSurface
+
+	//shared is the world every worker steps part of when p.Shared is set, in which case no rows are sent
+	shared *sharedWorld
+}
+
+//Information that each worker needs about their slice
+type sliceInfo struct {
+	index    int
+	start    int //the row of the world after the top halo
+	height   int
+	width    int
+	numAlive int
+}
+
+//Defines channels to send the original cells and the final rows to and from distributor and workers
+type workerIO struct {
+	inputCell chan cellState
+	output    chan worldPart
+
+	//fingerprints and verdict are only made when looking for cycles, see detectCycles
+	fingerprints chan<- fingerprint
+	verdict      <-chan verdict
+	powers       *hashPowers
+}
+
+//Part of the world at the start of turn, sent by a worker as copies of its rows,
+//the first of which is row start of the world.
+//Unbounded engines send the cells that aren't dead instead, which may lie outside of the world.
+type worldPart struct {
+	turn  int
+	start int
+	rows  [][]byte
+	cells []cellState
+	//alive is the number of live cells in the part, which is only counted for Pause
+	alive int
+}
+
+//The number of live cells in part of the world at the start of turn
+type count struct {
+	turn, alive int
+}
+
+//Returns copies of every row of worldslice apart from the two halo rows
+func stripRows(worldslice strip, width, height int) worldPart {
+	rows := make([][]byte, height-2)
+	for y := range rows {
+		rows[y] = make([]byte, width)
+		worldslice.readRow(y+1, rows[y])
+	}
+	return worldPart{rows: rows}
+}
+
+//Puts part into world, leaving out any cells that lie outside of it.
+//The rows are used as they are rather than copied, so they mustn't be changed afterwards.
+func (w *World) place(part worldPart) {
+	for y, row := range part.rows {
+		w.cells[part.start+y] = row
+	}
+	for _, c := range part.cells {
+		if c.X >= 0 && c.X < w.width && c.Y >= 0 && c.Y < w.height {
+			w.cells[c.Y][c.X] = c.state
+		}
+	}
+}
+
+//A cell that isn't dead along with the grey level its state is stored as
+type cellState struct {
+	Cell
+	state byte
+}
+
+func printGrid(world [][]byte) {
+	for _, row := range world {
+		for _, cell := range row {
+			if cell == Alive {
+				fmt.Print("1 ")
+			} else {
+				fmt.Print("0 ")
+			}
+		}
+		fmt.Println(" ")
+	}
+	fmt.Println(" ")
+}
+
+//getx and gety are used for checking neighbours when the x or y coordindate of the cell is at
+//the edges of the world. This is used because modding is slow
+func getx(x int, width int) int {
+	if x == width {
+		return 0
+	} else if x == -1 {
+		return width - 1
+	}
+	return x
+}
+
+func gety(y int, height int) int {
+	if y == height {
+		return 0
+	} else if y == -1 {
+		return height - 1
+	}
+	return y
+}
+
+// returns number of alive neighbours to a cell
+// west and east hold the cells just beyond the left and right edges of each row
+func numNeighbours(x int, y int, world [][]byte, west []byte, east []byte) int {
+	var num = 0
+	Width := len(world[0])
+	above, row, below := world[y-1], world[y], world[y+1]
+
+	var aboveLeft, left, belowLeft byte
+	if x == 0 {
+		aboveLeft, left, belowLeft = west[y-1], west[y], west[y+1]
+	} else {
+		aboveLeft, left, belowLeft = above[x-1], row[x-1], below[x-1]
+	}
+	var aboveRight, right, belowRight byte
+	if x == Width-1 {
+		aboveRight, right, belowRight = east[y-1], east[y], east[y+1]
+	} else {
+		aboveRight, right, belowRight = above[x+1], row[x+1], below[x+1]
+	}
+
+	if left == Alive {
+		num++
+	}
+	if belowLeft == Alive {
+		num++
+	}
+	if below[x] == Alive {
+		num++
+	}
+	if belowRight == Alive {
+		num++
+	}
+	if right == Alive {
+		num++
+	}
+	if aboveRight == Alive {
+		num++
+	}
+	if above[x] == Alive {
+		num++
+	}
+	if aboveLeft == Alive {
+		num++
+	}
+
+	return num
+}
+
+//Returns a slice of alive cells in the world
+func aliveCells(world [][]byte) []Cell {
+	var alive []Cell
+	for y := 0; y < len(world); y++ {
+		for x := 0; x < len(world[0]); x++ {
+			if world[y][x] == Alive {
+				alive = append(alive, Cell{X: x, Y: y})
+			}
+		}
+	}
+	return alive
+}
+
+//Returns a slice of every cell in the world that isn't dead, including dying cells
+func liveCells(world [][]byte) []cellState {
+	var live []cellState
+	for y := 0; y < len(world); y++ {
+		for x := 0; x < len(world[0]); x++ {
+			if world[y][x] != 0 {
+				live = append(live, cellState{Cell: Cell{X: x, Y: y}, state: world[y][x]})
+			}
+		}
+	}
+	return live
+}
+
+//Works out the next generation of every row of worldslice apart from the two halo rows,
+//writing the result into worldnew and returning its stats, with y counted from the first row after the halo
+func updateSlice(worldslice [][]byte, worldnew [][]byte, rule *table, west []byte, east []byte) stripStats {
+	var stats stripStats
+	for y := 1; y < len(worldslice)-1; y++ {
+		population, minX, maxX := 0, 0, 0
+		for x := 0; x < len(worldslice[y]); x++ {
+			neighbours := numNeighbours(x, y, worldslice, west, east)
+			old := worldslice[y][x]
+			next := rule[old][neighbours]
+			worldnew[y][x] = next
+			if next == Alive {
+				if population == 0 {
+					minX = x
+				}
+				maxX = x
+				population++
+				if old != Alive {
+					stats.births++
+				}
+			} else if old == Alive {
+				stats.deaths++
+			}
+		}
+		stats.addRow(y-1, population, minX, maxX)
+	}
+	return stats
+}
+
+//Synchronises the workers so when the world needs to be generated mid turn they are all on the same turn
+//Also tells the workers what to do depending on the requests made through Game
+func threadSyncer(s syncChans, p Params, k keyChans) {
+	for {
+		for i := 0; i < p.Threads; i++ {
+			<-s.threadsyncin
+		}
+		signal := nextSignal(s, k)
+		for i := 0; i < p.Threads; i++ {
+			s.threadsyncout <- signal
+		}
+	}
+}
+
+//Picks what the workers do at the start of a turn, once they have all got there, from the requests made through Game
+func nextSignal(s syncChans, k keyChans) byte {
+	select {
+	case <-s.periodicOutput:
+		return 1
+	case <-k.startSend:
+		return 2
+	case <-k.printTurns:
+		//The workers are stopped before they're told to pause, so none of them can get past the pause
+		k.pause.Add(1)
+		return 3
+	default:
+		return 0
+	}
+}
+
+func golWorker(workerIO workerIO, workerChans workerExchange, sliceInfo sliceInfo, p Params, s syncChans, k keyChans) {
+
+	shared := workerChans.shared
+	var worldslice strip
+	if shared != nil {
+		worldslice = window(shared.generations[0], sliceInfo.start, sliceInfo.start+sliceInfo.height-2)
+	} else {
+		worldslice = newStrip(p, sliceInfo.width, sliceInfo.height)
+	}
+	//west and east are the cells just beyond the left and right of each row, found from the first and last columns
+	west := make([]byte, sliceInfo.height)
+	east := make([]byte, sliceInfo.height)
+	first := make([]byte, sliceInfo.height)
+	last := make([]byte, sliceInfo.height)
+	//The rows sent to the workers above and below, taking turns between two of each.
+	//A row sent on one turn isn't written over until two turns later, by when its receiver must have
+	//finished with it, as the worker can't get past the turn in between without the receiver's rows for it
+	sendTops := [2][]byte{make([]byte, sliceInfo.width), make([]byte, sliceInfo.width)}
+	sendBots := [2][]byte{make([]byte, sliceInfo.width), make([]byte, sliceInfo.width)}
+	//Scratch space as wide as the strip
+	halo := make([]byte, sliceInfo.width)
+	edge := make([]byte, sliceInfo.width)
+	topEdge, bottomEdge := sliceInfo.index == 0, sliceInfo.index == p.Threads-1
+
+	//Receives live cells and puts them into the world
+	for i := 0; i < sliceInfo.numAlive; i++ {
+		currentcell := <-workerIO.inputCell
+		worldslice.set(currentcell.X, currentcell.Y, currentcell.state)
+	}
+	fixStripHalos(worldslice, p.Boundary, topEdge, bottomEdge, sliceInfo.height, halo, edge)
+
+	//stats are kept up to date by step, so they never need the whole strip to be looked at again
+	stats := scanStats(worldslice, sliceInfo.width, sliceInfo.height)
+	//sendStats records the stats of the world at the start of turn
+	sendStats := func(turn int) {
+		part := statsPart{turn: turn, index: sliceInfo.index, stats: stats}
+		if part.stats.population > 0 {
+			part.stats.minY += sliceInfo.start
+			part.stats.maxY += sliceInfo.start
+		}
+		k.stats <- part
+	}
+
+	detecting, capture := workerIO.verdict != nil, false
+	end := p.Turns
+	for turns := p.first; turns < end; turns++ {
+		if detecting {
+			f := stripFingerprint(worldslice, workerIO.powers, sliceInfo.start, sliceInfo.height, halo)
+			if capture {
+				f.cells = worldslice.live()
+				for i := range f.cells {
+					f.cells[i].Y += sliceInfo.start
+				}
+			}
+			workerIO.fingerprints <- f
+			v := <-workerIO.verdict
+			end, detecting, capture = v.end, v.detecting, v.capture
+			if turns == end {
+				break
+			}
+		}
+
+		var signal byte
+		if shared != nil {
+			signal = shared.barrier.wait(func() byte { return nextSignal(s, k) })
+			//Every worker has finished the last turn, so the rows either side of the strip can be read
+			shared.fillHalos(p, worldslice, sliceInfo.start, sliceInfo.height, turns, halo, edge)
+		} else {
+			s.threadsyncin <- true
+			signal = <-s.threadsyncout
+		}
+		//Outputs number of alive cells for periodic outputs
+		if signal == 1 {
+			s.periodicNumber <- count{turn: turns, alive: stats.population}
+
+			//Outputs current live cells for pgm file generation
+		} else if signal == 2 {
+			part := stripRows(worldslice, sliceInfo.width, sliceInfo.height)
+			part.turn, part.start = turns, sliceInfo.start
+			k.snapshots <- part
+		} else if signal == 3 {
+			//Hands over the strip when paused, so that Game can answer requests without waking the workers
+			part := stripRows(worldslice, sliceInfo.width, sliceInfo.height)
+			part.turn, part.start, part.alive = turns, sliceInfo.start, stats.population
+			k.turnsPrinted <- part
+		}
+		k.pause.Wait()
+		if recording(p, turns) {
+			part := framePart{turn: turns, cells: worldslice.live()}
+			for i := range part.cells {
+				part.cells[i].Y += sliceInfo.start
+			}
+			k.frames <- part
+		}
+		if recordingStats(p, turns) {
+			sendStats(turns)
+		}
+
+		worldslice.columns(first, last)
+		p.Boundary.sides(first, last, west, east, sliceInfo.start, p.ImageHeight, workerChans.edges, turns)
+		stats = worldslice.step(west, east)
+		if workerChans.edges != nil {
+			worldslice.columns(first, last)
+			workerChans.edges.store(turns+1, first[1:sliceInfo.height-1], last[1:sliceInfo.height-1], sliceInfo.start)
+		}
+		if shared != nil {
+			continue
+		}
+
+		sendTop, sendBot := sendTops[turns%2], sendBots[turns%2]
+		worldslice.readRow(1, sendTop)
+		worldslice.readRow(sliceInfo.height-2, sendBot)
+		//Every worker sends before it receives, as each channel has room for the one row sent on it each turn
+		workerChans.sTop <- sendTop
+		workerChans.sBot <- sendBot
+		worldslice.writeRow(0, <-workerChans.rTop)
+		worldslice.writeRow(sliceInfo.height-1, <-workerChans.rBot)
+		fixStripHalos(worldslice, p.Boundary, topEdge, bottomEdge, sliceInfo.height, halo, edge)
+
+	}
+	if recordingStats(p, end) {
+		sendStats(end)
+	}
+	//Sending the final rows back to distributor
+	part := stripRows(worldslice, sliceInfo.width, sliceInfo.height)
+	part.start = sliceInfo.start
+	workerIO.output <- part
+}
+
+// distributor divides the work between workers and interacts with other goroutines.
+func distributor(p Params, world [][]byte, s syncChans, result chan<- outcome, k keyChans) {
+	//Workers sharing the world wait for each other at its barrier instead
+	var shared *sharedWorld
+	if p.Shared {
+		shared = newSharedWorld(p, world)
+	} else {
+		go threadSyncer(s, p, k)
+	}
+
+	//The channels the workers will receive and send the alive cells on
+	var workerIO workerIO
+	workerIO.inputCell = make(chan cellState)
+	workerIO.output = make(chan worldPart, p.Threads)
+
+	rows, remainder := p.ImageHeight/p.Threads, p.ImageHeight%p.Threads
+
+	//Each worker is told whether to carry on by the cycle detector on its own channel,
+	//so that none of them can take another's verdict and run ahead
+	var verdicts []chan verdict
+	found := make(chan detected, 1)
+	if p.DetectCycles || p.StopOnCycle {
+		fingerprints := make(chan fingerprint, p.Threads)
+		verdicts = make([]chan verdict, p.Threads)
+		for i := range verdicts {
+			verdicts[i] = make(chan verdict, 1)
+		}
+		workerIO.fingerprints = fingerprints
+		workerIO.powers = newHashPowers(p.ImageWidth, p.ImageHeight)
+		go detectCycles(p, workerIO.powers, fingerprints, verdicts, found)
+	} else {
+		found <- detected{}
+	}
+
+	//rowsindex is used to append the correct amount of rows to each slice
+	rowsindex := 0
+
+	//Workers on a cross-surface need to see the edges of rows held by other workers
+	var edges *edgeColumns
+	if p.Boundary == CrossSurface {
+		edges = newEdgeColumns(world, p.first)
+	}
+
+	//down[i] carries the bottom row of worker i to the worker below it, and up[i] the top row of that worker
+	//back up to worker i. The last worker is above the first, and a single worker is its own neighbour
+	down := make([]chan []byte, p.Threads)
+	up := make([]chan []byte, p.Threads)
+	for i := range down {
+		down[i] = make(chan []byte, 1)
+		up[i] = make(chan []byte, 1)
+	}
+	for i := 0; i < p.Threads; i++ {
+
+		var worldslice [][]byte
+		start := rowsindex
+		//The first thread needs the final row from the other side of the world appended to its slice
+		if i == 0 {
+			worldslice = append(worldslice, world[len(world)-1:len(world)]...)
+		} else {
+			//appending the first additional row to a slice
+			worldslice = append(worldslice, world[rowsindex-1:rowsindex]...)
+		}
+
+		//Appends to each slice the correct number of rows
+		worldslice = append(worldslice, world[rowsindex:rowsindex+rows]...)
+
+		//the next thread will need to have the next set of rows above the last row appended
+		rowsindex += rows
+
+		//If the number of threads does not divide evenly, addtional rows are added to each slice
+		if remainder > 0 {
+			worldslice = append(worldslice, world[rowsindex:rowsindex+1]...)
+			//The next slice needs to start from further up
+			rowsindex++
+			//There are fewer remainder rows to append next time
+			remainder--
+		}
+
+		//The last thread needs the first row from the other side of the world appended to the end
+		if i == p.Threads-1 {
+			worldslice = append(worldslice, world[0:1]...)
+		} else {
+			//the other threads have the next row appended
+			worldslice = append(worldslice, world[rowsindex:rowsindex+1]...)
+		}
+
+		//Workers sharing the world find their cells in it already
+		var alive []cellState
+		if shared == nil {
+			alive = liveCells(worldslice)
+		}
+
+		var sliceInfo sliceInfo
+		sliceInfo.index = i
+		sliceInfo.start = start
+		sliceInfo.height = len(worldslice)
+		sliceInfo.width = len(worldslice[0])
+		sliceInfo.numAlive = len(alive)
+		if verdicts != nil {
+			workerIO.verdict = verdicts[i]
+		}
+
+		above := (i + p.Threads - 1) % p.Threads
+		var workerChans workerExchange
+		workerChans.edges = edges
+		workerChans.shared = shared
+		workerChans.rTop = down[above]
+		workerChans.sTop = up[above]
+		workerChans.rBot = up[i]
+		workerChans.sBot = down[i]
+		go golWorker(workerIO, workerChans, sliceInfo, p, s, k)
+		for _, alivecell := range alive {
+			workerIO.inputCell <- alivecell
+		}
+
+	}
+
+	//Creates a world to reform the slices together, taking each worker's rows as they are
+	worldnew := NewWorld(p.ImageWidth, p.ImageHeight)
+	for i := 0; i < p.Threads; i++ {
+		worldnew.place(<-workerIO.output)
+	}
+
+	// Return the world so the coordinates of cells that are still alive can be found.
+	//Turns skipped by a spaceship on a torus are made up for by moving it along
+	cycle := <-found
+	if cycle.shiftX != 0 || cycle.shiftY != 0 {
+		worldnew = shifted(worldnew, cycle.shiftX, cycle.shiftY)
+	}
+	result <- outcome{world: worldnew, alive: worldnew.Alive(), cycle: cycle.cycle}
+}
@@ -0,0 +1,329 @@
+package gol
+
+import (
+	"math/bits"
+)
+
+// DefaultMaxNodes is the number of quadtree nodes HashLife keeps before it collects garbage
+// when Params.MaxNodes is zero.
+const DefaultMaxNodes = 1 << 22
+
+//A square of 2^level x 2^level cells. Nodes of level 0 are single cells and have no children.
+//Nodes are shared wherever the same square turns up, so they must never be changed once made,
+//apart from remembering their result.
+type node struct {
+	level          uint
+	nw, ne, sw, se *node
+	population     int
+	//result is the centre of the node 2^resultStep turns on, which is worked out when it is first needed
+	result     *node
+	resultStep uint
+}
+
+//The children of a node, used to look up whether a square has been seen before
+type quad struct {
+	nw, ne, sw, se *node
+}
+
+//A world on an infinite plane stored as a quadtree, with root's top left cell at x, y
+type hashLife struct {
+	rule  Rule
+	nodes map[quad]*node
+	//empty holds the empty node of each level, which is never collected
+	empty []*node
+	//dead and alive are the two nodes of level 0
+	dead  *node
+	alive *node
+
+	root *node
+	x, y int
+
+	//limit is the number of nodes that triggers the next collection, which is never less than maxNodes
+	limit    int
+	maxNodes int
+}
+
+func newHashLife(rule Rule, maxNodes int) *hashLife {
+	if maxNodes <= 0 {
+		maxNodes = DefaultMaxNodes
+	}
+	h := &hashLife{rule: rule, nodes: make(map[quad]*node), limit: maxNodes, maxNodes: maxNodes}
+	h.dead = &node{}
+	h.alive = &node{population: 1}
+	h.empty = []*node{h.dead}
+	return h
+}
+
+//Returns the node with the given children, reusing an existing one if the square has been seen before
+func (h *hashLife) join(nw, ne, sw, se *node) *node {
+	key := quad{nw, ne, sw, se}
+	if n, ok := h.nodes[key]; ok {
+		return n
+	}
+	if len(h.nodes) >= h.limit {
+		h.collect()
+	}
+	n := &node{
+		level:      nw.level + 1,
+		nw:         nw,
+		ne:         ne,
+		sw:         sw,
+		se:         se,
+		population: nw.population + ne.population + sw.population + se.population,
+	}
+	h.nodes[key] = n
+	return n
+}
+
+func (h *hashLife) emptyNode(level uint) *node {
+	for uint(len(h.empty)) <= level {
+		e := h.empty[len(h.empty)-1]
+		h.empty = append(h.empty, &node{level: e.level + 1, nw: e, ne: e, sw: e, se: e})
+	}
+	return h.empty[level]
+}
+
+//Throws away every node the root doesn't need, along with every result that has been worked out.
+//Nodes still in use further up the stack of successor stay valid, they just won't be shared any more.
+func (h *hashLife) collect() {
+	h.nodes = make(map[quad]*node)
+	var keep func(n *node)
+	keep = func(n *node) {
+		if n.level == 0 || n.population == 0 {
+			return
+		}
+		key := quad{n.nw, n.ne, n.sw, n.se}
+		if _, ok := h.nodes[key]; ok {
+			return
+		}
+		n.result = nil
+		h.nodes[key] = n
+		keep(n.nw)
+		keep(n.ne)
+		keep(n.sw)
+		keep(n.se)
+	}
+	if h.root != nil {
+		keep(h.root)
+	}
+	//Leave room to grow, so that a root that needs most of maxNodes isn't collected over and over
+	h.limit = h.maxNodes
+	if 2*len(h.nodes) > h.limit {
+		h.limit = 2 * len(h.nodes)
+	}
+}
+
+//Builds the tree for cells, which may lie anywhere on the plane.
+//The cells are split between the quarters of each node rather than drawn into a grid first,
+//as they may be spread far apart.
+func (h *hashLife) load(cells []Cell) {
+	x, y, width, height := Bounds(cells)
+	size := width
+	if height > size {
+		size = height
+	}
+	level := uint(3)
+	for 1<<level < size {
+		level++
+	}
+	var build func(cells []Cell, x, y int, level uint) *node
+	build = func(cells []Cell, x, y int, level uint) *node {
+		if len(cells) == 0 {
+			return h.emptyNode(level)
+		}
+		if level == 0 {
+			return h.alive
+		}
+		half := 1 << (level - 1)
+		var quarters [4][]Cell
+		for _, c := range cells {
+			i := 0
+			if c.X >= x+half {
+				i++
+			}
+			if c.Y >= y+half {
+				i += 2
+			}
+			quarters[i] = append(quarters[i], c)
+		}
+		return h.join(build(quarters[0], x, y, level-1), build(quarters[1], x+half, y, level-1),
+			build(quarters[2], x, y+half, level-1), build(quarters[3], x+half, y+half, level-1))
+	}
+	h.root = build(cells, x, y, level)
+	h.x, h.y = x, y
+}
+
+//Returns every live cell, which may lie anywhere on the plane
+func (h *hashLife) cells() []Cell {
+	var cells []Cell
+	var visit func(n *node, x, y int)
+	visit = func(n *node, x, y int) {
+		if n.population == 0 {
+			return
+		}
+		if n.level == 0 {
+			cells = append(cells, Cell{X: x, Y: y})
+			return
+		}
+		half := 1 << (n.level - 1)
+		visit(n.nw, x, y)
+		visit(n.ne, x+half, y)
+		visit(n.sw, x, y+half)
+		visit(n.se, x+half, y+half)
+	}
+	visit(h.root, h.x, h.y)
+	return cells
+}
+
+func (h *hashLife) live() []cellState {
+	var live []cellState
+	for _, c := range h.cells() {
+		live = append(live, cellState{Cell: c, state: Alive})
+	}
+	return live
+}
+
+//Moves the whole plane on by 2^j turns
+func (h *hashLife) advance(j uint) {
+	//The result of a node is its centre half, which must have room for the pattern to spread by 2^j cells
+	//in every direction, so the root is grown until the pattern fits in the middle quarter
+	for h.root.level < j+3 || !h.centred() {
+		h.expand()
+	}
+	shift := 1 << (h.root.level - 2)
+	h.root = h.successor(h.root, j)
+	h.x += shift
+	h.y += shift
+}
+
+//Reports whether every live cell lies in the middle quarter of the root
+func (h *hashLife) centred() bool {
+	r := h.root
+	return r.nw.se.se.population+r.ne.sw.sw.population+r.sw.ne.ne.population+r.se.nw.nw.population == r.population
+}
+
+//Surrounds the root with empty space, doubling its size
+func (h *hashLife) expand() {
+	r := h.root
+	e := h.emptyNode(r.level - 1)
+	h.root = h.join(
+		h.join(e, e, e, r.nw),
+		h.join(e, e, r.ne, e),
+		h.join(e, r.sw, e, e),
+		h.join(r.se, e, e, e),
+	)
+	shift := 1 << (r.level - 1)
+	h.x -= shift
+	h.y -= shift
+}
+
+//Returns the middle half of a node
+func (h *hashLife) centre(n *node) *node {
+	return h.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+}
+
+//Returns the centre of n, a node of level 2 or more, as it will be 2^j turns later.
+//j must be no more than n.level-2, which is as far as the cells inside n can see.
+func (h *hashLife) successor(n *node, j uint) *node {
+	if n.population == 0 {
+		return h.emptyNode(n.level - 1)
+	}
+	if n.result != nil && n.resultStep == j {
+		return n.result
+	}
+
+	var result *node
+	if n.level == 2 {
+		result = h.base(n)
+	} else {
+		//The nine overlapping squares of half the size of n, from the top left to the bottom right
+		n00, n01, n02 := n.nw, h.join(n.nw.ne, n.ne.nw, n.nw.se, n.ne.sw), n.ne
+		n10, n11, n12 := h.join(n.nw.sw, n.nw.se, n.sw.nw, n.sw.ne), h.centre(n), h.join(n.ne.sw, n.ne.se, n.se.nw, n.se.ne)
+		n20, n21, n22 := n.sw, h.join(n.sw.ne, n.se.nw, n.sw.se, n.se.sw), n.se
+
+		var c [9]*node
+		if j == n.level-2 {
+			//At full speed the squares are moved on by half of the turns, and the four squares they
+			//make up are moved on by the other half
+			for i, m := range []*node{n00, n01, n02, n10, n11, n12, n20, n21, n22} {
+				c[i] = h.successor(m, j-1)
+			}
+		} else {
+			//Otherwise the squares are only cut down to size, and all of the turns happen afterwards
+			for i, m := range []*node{n00, n01, n02, n10, n11, n12, n20, n21, n22} {
+				c[i] = h.centre(m)
+			}
+		}
+		next := j
+		if j == n.level-2 {
+			next = j - 1
+		}
+		result = h.join(
+			h.successor(h.join(c[0], c[1], c[3], c[4]), next),
+			h.successor(h.join(c[1], c[2], c[4], c[5]), next),
+			h.successor(h.join(c[3], c[4], c[6], c[7]), next),
+			h.successor(h.join(c[4], c[5], c[7], c[8]), next),
+		)
+	}
+	n.result, n.resultStep = result, j
+	return result
+}
+
+//Works out one turn of the middle 2x2 cells of a 4x4 node directly from the rule
+func (h *hashLife) base(n *node) *node {
+	var grid [4][4]int
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			child := [2][2]*node{{n.nw, n.ne}, {n.sw, n.se}}[y/2][x/2]
+			grid[y][x] = [2][2]*node{{child.nw, child.ne}, {child.sw, child.se}}[y%2][x%2].population
+		}
+	}
+	var next [2][2]*node
+	for y := 1; y <= 2; y++ {
+		for x := 1; x <= 2; x++ {
+			neighbours := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if dx != 0 || dy != 0 {
+						neighbours += grid[y+dy][x+dx]
+					}
+				}
+			}
+			if (grid[y][x] == 0 && h.rule.Born(neighbours)) || (grid[y][x] == 1 && h.rule.Survives(neighbours)) {
+				next[y-1][x-1] = h.alive
+			} else {
+				next[y-1][x-1] = h.dead
+			}
+		}
+	}
+	return h.join(next[0][0], next[0][1], next[1][0], next[1][1])
+}
+
+//Simulates the turns from start up to p.Turns with HashLife, taking the biggest jumps it can.
+//Requests from Game are answered between jumps, and jumps never pass over a turn that should be recorded.
+func hashLifeEngine(p Params, start State, s syncChans, result chan<- outcome, k keyChans) {
+	world := start.World
+	h := newHashLife(p.Rule, p.MaxNodes)
+	h.load(start.Alive())
+
+	for turn := start.Turn; turn < p.Turns; {
+		turns := p.Turns - turn
+		if p.FrameEvery > 0 {
+			//Jumps stop at the next multiple of FrameEvery, which a resumed game may not start on
+			turns = p.FrameEvery - turn%p.FrameEvery
+		}
+		if p.Turns-turn < turns {
+			turns = p.Turns - turn
+		}
+		for j := bits.Len(uint(turns)) - 1; j >= 0; j-- {
+			if turns&(1<<uint(j)) == 0 {
+				continue
+			}
+			serveRequests(p, s, k, turn, h.live)
+			h.advance(uint(j))
+			turn += 1 << uint(j)
+		}
+	}
+
+	result <- cropped(h.cells(), world.Width(), world.Height())
+}
@@ -0,0 +1,186 @@
+package gol
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Rule is a Life-like or Generations rule: which neighbour counts bring a dead cell to life,
+// which keep a live cell alive, and how many states a cell passes through before it is dead.
+// The zero Rule is Conway's Game of Life, B3/S23.
+type Rule struct {
+	//birth and survival are bitmasks with bit n set if n neighbours causes a birth or survival
+	birth    uint16
+	survival uint16
+	//states counts dead and alive as well as the dying states of Generations rules
+	states int
+	//set is false for the zero Rule, which stands in for Conway
+	set bool
+}
+
+// Well known Life-like and Generations rules.
+var (
+	Conway      = MustParseRule("B3/S23")
+	HighLife    = MustParseRule("B36/S23")
+	Seeds       = MustParseRule("B2/S")
+	DayNight    = MustParseRule("B3678/S34678")
+	Maze        = MustParseRule("B3/S12345")
+	BriansBrain = MustParseRule("B2/S/C3")
+	StarWars    = MustParseRule("B2/S345/C4")
+)
+
+// ParseRule parses a rulestring in either B/S notation, such as "B36/S23",
+// or the older S/B notation, such as "23/36".
+// Generations rules add the number of states as a third part, such as "B2/S/C3" or "/2/3".
+func ParseRule(s string) (Rule, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 2 && len(parts) != 3 {
+		return Rule{}, errors.New("rule " + strconv.Quote(s) + " should have two or three parts separated by '/'")
+	}
+
+	var birth, survival string
+	first, second := strings.ToUpper(parts[0]), strings.ToUpper(parts[1])
+	switch {
+	case strings.HasPrefix(first, "B") && strings.HasPrefix(second, "S"):
+		birth, survival = first[1:], second[1:]
+	case strings.HasPrefix(first, "S") && strings.HasPrefix(second, "B"):
+		birth, survival = second[1:], first[1:]
+	default:
+		//S/B notation lists the survival counts first
+		birth, survival = second, first
+	}
+
+	r := Rule{states: 2}
+	var err error
+	if r.birth, err = parseCounts(birth); err != nil {
+		return Rule{}, errors.New("rule " + strconv.Quote(s) + ": " + err.Error())
+	}
+	if r.survival, err = parseCounts(survival); err != nil {
+		return Rule{}, errors.New("rule " + strconv.Quote(s) + ": " + err.Error())
+	}
+	if len(parts) == 3 {
+		states, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(parts[2]), "C"))
+		if err != nil || states < 2 || states > 256 {
+			return Rule{}, errors.New("rule " + strconv.Quote(s) + ": number of states must be between 2 and 256")
+		}
+		r.states = states
+	}
+	r.set = true
+	return r, nil
+}
+
+// MustParseRule is like ParseRule but panics if the rulestring is invalid.
+func MustParseRule(s string) Rule {
+	r, err := ParseRule(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+//Turns a list of neighbour counts such as "236" into a bitmask
+func parseCounts(counts string) (uint16, error) {
+	var mask uint16
+	for _, c := range counts {
+		if c < '0' || c > '8' {
+			return 0, errors.New("invalid neighbour count " + strconv.QuoteRune(c))
+		}
+		mask |= 1 << uint(c-'0')
+	}
+	return mask, nil
+}
+
+//Replaces the zero Rule with Conway
+func (r Rule) orDefault() Rule {
+	if !r.set {
+		return Conway
+	}
+	return r
+}
+
+// Born reports whether a dead cell with n live neighbours comes to life.
+func (r Rule) Born(n int) bool {
+	return r.orDefault().birth&(1<<uint(n)) != 0
+}
+
+// Survives reports whether a live cell with n live neighbours stays alive.
+func (r Rule) Survives(n int) bool {
+	return r.orDefault().survival&(1<<uint(n)) != 0
+}
+
+// States returns the number of states a cell can be in, which is 2 for Life-like rules.
+// State 0 is dead, state 1 is alive, and any further states are dying.
+func (r Rule) States() int {
+	return r.orDefault().states
+}
+
+// Level returns the grey level a cell in the given state is stored as.
+// Dead cells are 0, live cells are Alive, and dying cells get darker as they decay.
+func (r Rule) Level(state int) byte {
+	if state <= 0 {
+		return 0
+	}
+	return byte(255 - (state-1)*255/(r.States()-1))
+}
+
+// String returns the rule in B/S notation, or B/S/C notation for Generations rules.
+func (r Rule) String() string {
+	r = r.orDefault()
+	var b strings.Builder
+	b.WriteString("B")
+	for n := 0; n <= 8; n++ {
+		if r.birth&(1<<uint(n)) != 0 {
+			b.WriteString(strconv.Itoa(n))
+		}
+	}
+	b.WriteString("/S")
+	for n := 0; n <= 8; n++ {
+		if r.survival&(1<<uint(n)) != 0 {
+			b.WriteString(strconv.Itoa(n))
+		}
+	}
+	if r.states > 2 {
+		b.WriteString("/C")
+		b.WriteString(strconv.Itoa(r.states))
+	}
+	return b.String()
+}
+
+// Set parses s into r so that a Rule can be used as a command line flag.
+func (r *Rule) Set(s string) error {
+	parsed, err := ParseRule(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// table is a Rule worked out in advance for every grey level a cell can be stored as
+// and every number of live neighbours it can have.
+type table [256][9]byte
+
+func (r Rule) table() *table {
+	r = r.orDefault()
+	var t table
+	for level := range t {
+		for n := range t[level] {
+			t[level][n] = byte(level)
+		}
+	}
+	for n := 0; n <= 8; n++ {
+		if r.Born(n) {
+			t[0][n] = Alive
+		}
+		if !r.Survives(n) {
+			//A live cell that doesn't survive starts dying, or just dies if there are only two states
+			t[Alive][n] = r.Level(2 % r.states)
+		}
+		//Dying cells get one state older every turn whatever their neighbours are doing
+		for state := 2; state < r.states; state++ {
+			t[r.Level(state)][n] = r.Level((state + 1) % r.states)
+		}
+	}
+	return &t
+}
@@ -0,0 +1,119 @@
+package gol
+
+import (
+	"sync"
+)
+
+//A barrier that the same number of goroutines wait at over and over again
+type barrier struct {
+	mu      sync.Mutex
+	arrived *sync.Cond
+	parties int
+	waiting int
+	//round counts the times every goroutine has got to the barrier, so that those woken know they can go
+	round  int
+	signal byte
+}
+
+func newBarrier(parties int) *barrier {
+	b := &barrier{parties: parties}
+	b.arrived = sync.NewCond(&b.mu)
+	return b
+}
+
+//Blocks until every goroutine sharing the barrier has called wait.
+//The last to get there calls decide, if it isn't nil, before any of them go,
+//and wait returns what it decided to all of them
+func (b *barrier) wait(decide func() byte) byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	round := b.round
+	b.waiting++
+	if b.waiting == b.parties {
+		b.signal = 0
+		if decide != nil {
+			b.signal = decide()
+		}
+		b.waiting = 0
+		b.round++
+		b.arrived.Broadcast()
+		return b.signal
+	}
+	for round == b.round {
+		b.arrived.Wait()
+	}
+	return b.signal
+}
+
+//The world shared between the workers when p.Shared is set.
+//Every worker steps its own rows of it, and reads the rows either side of them from it at the start of each turn
+type sharedWorld struct {
+	//generations holds world with its current and next generations each way round,
+	//so generations[n%2] has the current generation once the workers have stepped their rows n times
+	generations [2]strip
+	barrier     *barrier
+}
+
+func newSharedWorld(p Params, world [][]byte) *sharedWorld {
+	s := newStrip(p, p.ImageWidth, p.ImageHeight)
+	for y, row := range world {
+		s.writeRow(y, row)
+	}
+	return &sharedWorld{generations: [2]strip{s, flipped(s)}, barrier: newBarrier(p.Threads)}
+}
+
+//Returns s with its current and next generations swapped round, sharing every row with it
+func flipped(s strip) strip {
+	switch s := s.(type) {
+	case *byteStrip:
+		f := *s
+		f.rows, f.next = s.next, s.rows
+		return &f
+	case *bitStrip:
+		f := *s
+		f.rows, f.next = s.next, s.rows
+		return &f
+	}
+	panic("unknown strip")
+}
+
+//Returns a strip made of rows from to to of s, which it shares with s, along with a halo row of its own above and below.
+//The halos aren't shared, as the rows just beyond a strip are another worker's and steps may write to halos
+func window(s strip, from, to int) strip {
+	switch s := s.(type) {
+	case *byteStrip:
+		w := *s
+		w.rows, w.next = withByteHalos(s.rows[from:to]), withByteHalos(s.next[from:to])
+		return &w
+	case *bitStrip:
+		w := *s
+		w.rows, w.next = withBitHalos(s.rows[from:to]), withBitHalos(s.next[from:to])
+		return &w
+	}
+	panic("unknown strip")
+}
+
+func withByteHalos(rows [][]byte) [][]byte {
+	width := len(rows[0])
+	return append(append([][]byte{make([]byte, width)}, rows...), make([]byte, width))
+}
+
+func withBitHalos(rows [][]uint64) [][]uint64 {
+	words := len(rows[0])
+	return append(append([][]uint64{make([]uint64, words)}, rows...), make([]uint64, words))
+}
+
+//Copies the rows just above and below the strip of the worker starting at row start of the world into its halos,
+//at the start of turn, and fixes them up for the boundary. halo and edge are scratch space as wide as the world
+func (w *sharedWorld) fillHalos(p Params, worldslice strip, start, height, turn int, halo, edge []byte) {
+	current := w.generations[(turn-p.first)%2]
+	current.readRow((start-1+p.ImageHeight)%p.ImageHeight, halo)
+	worldslice.writeRow(0, halo)
+	current.readRow((start+height-2)%p.ImageHeight, halo)
+	worldslice.writeRow(height-1, halo)
+	fixStripHalos(worldslice, p.Boundary, start == 0, start+height-2 == p.ImageHeight, height, halo, edge)
+	//Steps of the bits backend write to every row they are given, so none can start until the rows have all been read
+	if p.Backend == BitBackend {
+		w.barrier.wait(nil)
+	}
+}
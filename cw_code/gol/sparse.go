@@ -0,0 +1,159 @@
+package gol
+
+import (
+	"math/bits"
+)
+
+//The width and height of a tile, which is one bit of a uint64 per cell
+const tileSize = 64
+
+//A tileSize x tileSize square of cells, with cell x of a row in bit x of that row's word
+type tile [tileSize]uint64
+
+//The position of a tile, counted in tiles from the one with its top left cell at 0, 0
+type tileKey struct {
+	x, y int
+}
+
+//An infinite plane that only stores the tiles with live cells in them
+type sparseWorld struct {
+	tiles map[tileKey]*tile
+	rule  bitRule
+}
+
+func newSparseWorld(rule Rule) *sparseWorld {
+	return &sparseWorld{tiles: make(map[tileKey]*tile), rule: newBitRule(rule)}
+}
+
+//Returns the tile a cell lies in and the cell's position within it
+func tileOf(x, y int) (tileKey, int, int) {
+	//Shifting rounds towards minus infinity, so negative coordinates end up in the right tile
+	return tileKey{x >> 6, y >> 6}, x & (tileSize - 1), y & (tileSize - 1)
+}
+
+func (s *sparseWorld) set(x, y int) {
+	key, tx, ty := tileOf(x, y)
+	t, ok := s.tiles[key]
+	if !ok {
+		t = &tile{}
+		s.tiles[key] = t
+	}
+	t[ty] |= 1 << uint(tx)
+}
+
+func (s *sparseWorld) load(cells []Cell) {
+	for _, c := range cells {
+		s.set(c.X, c.Y)
+	}
+}
+
+//Returns every live cell, which may lie anywhere on the plane
+func (s *sparseWorld) cells() []Cell {
+	var cells []Cell
+	for key, t := range s.tiles {
+		for y, row := range t {
+			for row != 0 {
+				x := bits.TrailingZeros64(row)
+				cells = append(cells, Cell{X: key.x*tileSize + x, Y: key.y*tileSize + y})
+				row &= row - 1
+			}
+		}
+	}
+	return cells
+}
+
+func (s *sparseWorld) live() []cellState {
+	var live []cellState
+	for _, c := range s.cells() {
+		live = append(live, cellState{Cell: c, state: Alive})
+	}
+	return live
+}
+
+//Works out the next generation of every tile that has live cells in it or next to it
+func (s *sparseWorld) step() {
+	next := make(map[tileKey]*tile, len(s.tiles))
+	for key := range s.tiles {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				k := tileKey{key.x + dx, key.y + dy}
+				if _, done := next[k]; done {
+					continue
+				}
+				t := s.stepTile(k)
+				//Tiles that die out are kept until the end of the turn so they aren't worked out again
+				next[k] = t
+			}
+		}
+	}
+	for k, t := range next {
+		if t == nil {
+			delete(next, k)
+		}
+	}
+	s.tiles = next
+}
+
+//Returns the next generation of the tile at key, or nil if it has no live cells
+func (s *sparseWorld) stepTile(key tileKey) *tile {
+	//around holds the tile and its eight neighbours, with the tile itself in the middle
+	var around [3][3]*tile
+	empty := &tile{}
+	for dy := 0; dy < 3; dy++ {
+		for dx := 0; dx < 3; dx++ {
+			if t, ok := s.tiles[tileKey{key.x + dx - 1, key.y + dy - 1}]; ok {
+				around[dy][dx] = t
+			} else {
+				around[dy][dx] = empty
+			}
+		}
+	}
+	//Returns row y of the tile, or of the tile above or below it for rows just beyond its edges,
+	//along with the same row of the tiles to its left and right
+	row := func(y int) (uint64, uint64, uint64) {
+		r := 1
+		if y < 0 {
+			r, y = 0, tileSize-1
+		} else if y >= tileSize {
+			r, y = 2, 0
+		}
+		return around[r][0][y], around[r][1][y], around[r][2][y]
+	}
+
+	var next tile
+	alive := false
+	for y := 0; y < tileSize; y++ {
+		var neighbours [8]uint64
+		i := 0
+		for dy := -1; dy <= 1; dy++ {
+			west, centre, east := row(y + dy)
+			neighbours[i] = centre<<1 | west>>63
+			i++
+			if dy != 0 {
+				neighbours[i] = centre
+				i++
+			}
+			neighbours[i] = centre>>1 | east<<63
+			i++
+		}
+		_, centre, _ := row(y)
+		next[y] = s.rule.next(centre, &neighbours)
+		alive = alive || next[y] != 0
+	}
+	if !alive {
+		return nil
+	}
+	return &next
+}
+
+//Simulates the turns from start up to p.Turns on an infinite plane, answering requests from Game between turns
+func sparseEngine(p Params, start State, s syncChans, result chan<- outcome, k keyChans) {
+	world := start.World
+	plane := newSparseWorld(p.Rule)
+	plane.load(start.Alive())
+	for turn := start.Turn; turn < p.Turns; turn++ {
+		serveRequests(p, s, k, turn, plane.live)
+		plane.step()
+	}
+	result <- cropped(plane.cells(), world.Width(), world.Height())
+}
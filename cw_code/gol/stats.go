@@ -0,0 +1,165 @@
+package gol
+
+import (
+	"math/bits"
+)
+
+// Stats describes the world at the start of a turn, recorded for Game.Stats.
+type Stats struct {
+	Turn int
+	//Population is the number of live cells. Births and Deaths are how many cells came alive
+	//and stopped being alive on the turn before, which are 0 for the turn the game started at.
+	Population int
+	Births     int
+	Deaths     int
+	//X, Y, Width and Height are the bounding box of the live cells, which is 0x0 if there are none
+	X, Y, Width, Height int
+	//Workers holds the population of each worker's strip, from the top of the world down
+	Workers []int
+}
+
+//The statistics of the live cells in a worker's strip
+type stripStats struct {
+	population, births, deaths int
+	//The bounding box of the live cells, which is only set if population isn't 0
+	minX, minY, maxX, maxY int
+}
+
+//Adds in the stats of another part of the world
+func (s *stripStats) merge(other stripStats) {
+	s.births += other.births
+	s.deaths += other.deaths
+	if other.population == 0 {
+		return
+	}
+	if s.population == 0 {
+		s.minX, s.minY, s.maxX, s.maxY = other.minX, other.minY, other.maxX, other.maxY
+	} else {
+		s.minX, s.minY = min(s.minX, other.minX), min(s.minY, other.minY)
+		s.maxX, s.maxY = max(s.maxX, other.maxX), max(s.maxY, other.maxY)
+	}
+	s.population += other.population
+}
+
+//Adds a row at y with population live cells between minX and maxX
+func (s *stripStats) addRow(y, population, minX, maxX int) {
+	s.merge(stripStats{population: population, minX: minX, minY: y, maxX: maxX, maxY: y})
+}
+
+//Works out the population and bounding box of a strip's own rows, with y counted from the first of them.
+//Workers only need this once, as step keeps track of them from then on.
+func scanStats(worldslice strip, width, height int) stripStats {
+	var stats stripStats
+	row := make([]byte, width)
+	for y := 1; y < height-1; y++ {
+		worldslice.readRow(y, row)
+		population, minX, maxX := 0, 0, 0
+		for x, state := range row {
+			if state == Alive {
+				if population == 0 {
+					minX = x
+				}
+				maxX = x
+				population++
+			}
+		}
+		stats.addRow(y-1, population, minX, maxX)
+	}
+	return stats
+}
+
+//Adds a row of a bitStrip at y, along with the births and deaths since old.
+//Only the bits of the last word in lastMask hold cells.
+func (s *stripStats) addWords(y int, old, next []uint64, lastWord int, lastMask uint64) {
+	population, minX, maxX := 0, 0, 0
+	for i, word := range next {
+		was := old[i]
+		if i == lastWord {
+			was &= lastMask
+		}
+		s.births += bits.OnesCount64(word &^ was)
+		s.deaths += bits.OnesCount64(was &^ word)
+		if word == 0 {
+			continue
+		}
+		if population == 0 {
+			minX = i*64 + bits.TrailingZeros64(word)
+		}
+		maxX = i*64 + 63 - bits.LeadingZeros64(word)
+		population += bits.OnesCount64(word)
+	}
+	s.addRow(y, population, minX, maxX)
+}
+
+//Part of the stats of a turn sent by a worker, with the bounding box in world coordinates
+type statsPart struct {
+	turn  int
+	index int
+	stats stripStats
+}
+
+//Reports whether the stats at the start of turn should be recorded
+func recordingStats(p Params, turn int) bool {
+	return p.StatsEvery > 0 && turn%p.StatsEvery == 0
+}
+
+// Stats returns a channel that receives the stats of the world every p.StatsEvery turns,
+// starting with the world the game started with and ending with the final world if its turn is a multiple of p.StatsEvery.
+// The channel is closed once the game has finished. The workers wait for the stats to be received,
+// so the channel must be read until it is closed. Without p.StatsEvery the channel is closed straight away.
+func (g *Game) Stats() <-chan Stats {
+	return g.stats
+}
+
+//Adds up the parts of the stats sent by the workers and hands them on to Stats in order of turn.
+//A worker can get a turn ahead of the others at the end of the game, so parts are kept by turn until every worker has sent one.
+func (g *Game) collectStats() {
+	defer close(g.stats)
+	if g.params.StatsEvery <= 0 {
+		return
+	}
+	p := g.params
+	turns := make(map[int][]statsPart)
+	//A resumed game records from the first multiple of StatsEvery it reaches
+	next := (p.first + p.StatsEvery - 1) / p.StatsEvery * p.StatsEvery
+	send := func(part statsPart) {
+		turns[part.turn] = append(turns[part.turn], part)
+		for len(turns[next]) == p.Threads {
+			g.stats <- combineStats(next, turns[next])
+			delete(turns, next)
+			next += p.StatsEvery
+		}
+	}
+	for {
+		select {
+		case part := <-g.keys.stats:
+			send(part)
+		case <-g.done:
+			//Every part was sent before the game finished, but some may still be waiting to be received
+			for {
+				select {
+				case part := <-g.keys.stats:
+					send(part)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+//Puts together the stats of a turn from the part sent by each worker
+func combineStats(turn int, parts []statsPart) Stats {
+	stats := Stats{Turn: turn, Workers: make([]int, len(parts))}
+	var whole stripStats
+	for _, part := range parts {
+		stats.Workers[part.index] = part.stats.population
+		whole.merge(part.stats)
+	}
+	stats.Population, stats.Births, stats.Deaths = whole.population, whole.births, whole.deaths
+	if whole.population > 0 {
+		stats.X, stats.Y = whole.minX, whole.minY
+		stats.Width, stats.Height = whole.maxX-whole.minX+1, whole.maxY-whole.minY+1
+	}
+	return stats
+}
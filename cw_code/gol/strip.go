@@ -0,0 +1,142 @@
+package gol
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Backend decides how workers store their part of the world.
+// The zero Backend is ByteBackend.
+type Backend int
+
+const (
+	// ByteBackend stores each cell in a byte, so it supports every rule.
+	ByteBackend Backend = iota
+	// BitBackend packs 64 cells into each uint64 and works out 64 cells at a time with bitwise adders.
+	// It only supports Life-like rules with two states.
+	BitBackend
+)
+
+var backendNames = []string{
+	ByteBackend: "byte",
+	BitBackend:  "bits",
+}
+
+// ParseBackend returns the backend with the given name: byte or bits.
+func ParseBackend(s string) (Backend, error) {
+	for b, name := range backendNames {
+		if s == name {
+			return Backend(b), nil
+		}
+	}
+	return ByteBackend, errors.New("unknown backend " + strconv.Quote(s) + ", expected byte or bits")
+}
+
+// String returns the name of the backend.
+func (b Backend) String() string {
+	if b < 0 || int(b) >= len(backendNames) {
+		return "Backend(" + strconv.Itoa(int(b)) + ")"
+	}
+	return backendNames[b]
+}
+
+// Set parses s into b so that a Backend can be used as a command line flag.
+func (b *Backend) Set(s string) error {
+	parsed, err := ParseBackend(s)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+// strip is the part of the world that one worker simulates: its own rows with a halo row above and below.
+// Rows are numbered from the top halo, so the worker's own rows are 1 to height-2.
+type strip interface {
+	get(x, y int) byte
+	set(x, y int, state byte)
+	readRow(y int, dst []byte)
+	writeRow(y int, src []byte)
+	//columns fills in the first and last cell of every row, halos included
+	columns(first []byte, last []byte)
+	//live only looks at the worker's own rows, with y counted from the first of them
+	live() []cellState
+	//step works out the next generation of the worker's own rows, given the cells just beyond the
+	//left and right of every row, and returns the stats of the new generation in the same coordinates as live.
+	//The halos are left for the worker to fill in
+	step(west []byte, east []byte) stripStats
+}
+
+func newStrip(p Params, width int, height int) strip {
+	if p.Backend == BitBackend {
+		return newBitStrip(p.Rule, width, height)
+	}
+	return newByteStrip(p.Rule, width, height)
+}
+
+//Fixes up the halo rows of a strip that lie beyond the top or bottom of the world
+//halo and edge are scratch space as wide as the strip
+func fixStripHalos(s strip, b Boundary, top bool, bottom bool, height int, halo []byte, edge []byte) {
+	if top {
+		s.readRow(0, halo)
+		s.readRow(1, edge)
+		b.fixHalo(halo, edge)
+		s.writeRow(0, halo)
+	}
+	if bottom {
+		s.readRow(height-1, halo)
+		s.readRow(height-2, edge)
+		b.fixHalo(halo, edge)
+		s.writeRow(height-1, halo)
+	}
+}
+
+//A strip storing a byte per cell, with a second buffer the next generation is written into
+type byteStrip struct {
+	rows [][]byte
+	next [][]byte
+	rule *table
+}
+
+func newByteStrip(rule Rule, width int, height int) *byteStrip {
+	s := &byteStrip{rule: rule.table()}
+	s.rows = make([][]byte, height)
+	s.next = make([][]byte, height)
+	for i := 0; i < height; i++ {
+		s.rows[i] = make([]byte, width)
+		s.next[i] = make([]byte, width)
+	}
+	return s
+}
+
+func (s *byteStrip) get(x, y int) byte {
+	return s.rows[y][x]
+}
+
+func (s *byteStrip) set(x, y int, state byte) {
+	s.rows[y][x] = state
+}
+
+func (s *byteStrip) readRow(y int, dst []byte) {
+	copy(dst, s.rows[y])
+}
+
+func (s *byteStrip) writeRow(y int, src []byte) {
+	copy(s.rows[y], src)
+}
+
+func (s *byteStrip) columns(first []byte, last []byte) {
+	for y, row := range s.rows {
+		first[y], last[y] = row[0], row[len(row)-1]
+	}
+}
+
+func (s *byteStrip) live() []cellState {
+	return liveCells(s.rows[1 : len(s.rows)-1])
+}
+
+func (s *byteStrip) step(west []byte, east []byte) stripStats {
+	stats := updateSlice(s.rows, s.next, s.rule, west, east)
+	s.rows, s.next = s.next, s.rows
+	return stats
+}
@@ -0,0 +1,121 @@
+package gol
+
+// Alive is the grey level a live cell is stored as. Dead cells are 0.
+// Generations rules store dying cells as the levels in between, see Rule.Level.
+const Alive byte = 255
+
+// Cell is the coordinate of a cell in the world.
+type Cell struct {
+	X, Y int
+}
+
+// World is a grid of cells. What lies beyond its edges is decided by the Boundary in Params.
+// Each cell is stored as the grey level of its state.
+type World struct {
+	width, height int
+	cells         [][]byte
+}
+
+// NewWorld returns an empty world of the given size.
+func NewWorld(width, height int) *World {
+	cells := make([][]byte, height)
+	for i := range cells {
+		cells[i] = make([]byte, width)
+	}
+	return &World{width: width, height: height, cells: cells}
+}
+
+// Width returns the number of cells in each row of the world.
+func (w *World) Width() int {
+	return w.width
+}
+
+// Height returns the number of rows in the world.
+func (w *World) Height() int {
+	return w.height
+}
+
+// Get returns the grey level of the cell at x, y.
+func (w *World) Get(x, y int) byte {
+	return w.cells[y][x]
+}
+
+// Set changes the grey level of the cell at x, y.
+func (w *World) Set(x, y int, state byte) {
+	w.cells[y][x] = state
+}
+
+// Row returns the grey levels of row y of the world.
+// The row is the world's own, so changing it changes the world.
+func (w *World) Row(y int) []byte {
+	return w.cells[y]
+}
+
+// Alive returns the coordinates of every live cell in the world.
+func (w *World) Alive() []Cell {
+	return aliveCells(w.cells)
+}
+
+// Bounds returns the top left corner and the size of the smallest rectangle holding every one of cells.
+// The size is 0x0 if there are no cells.
+func Bounds(cells []Cell) (x, y, width, height int) {
+	if len(cells) == 0 {
+		return 0, 0, 0, 0
+	}
+	minX, minY, maxX, maxY := cells[0].X, cells[0].Y, cells[0].X, cells[0].Y
+	for _, c := range cells[1:] {
+		if c.X < minX {
+			minX = c.X
+		} else if c.X > maxX {
+			maxX = c.X
+		}
+		if c.Y < minY {
+			minY = c.Y
+		} else if c.Y > maxY {
+			maxY = c.Y
+		}
+	}
+	return minX, minY, maxX - minX + 1, maxY - minY + 1
+}
+
+// Region returns a world just big enough to hold every one of cells,
+// along with the cell of the plane that its top left corner stands for.
+// The world is 1x1 and empty if there are no cells.
+func Region(cells []Cell) (*World, Cell) {
+	x, y, width, height := Bounds(cells)
+	if width == 0 {
+		return NewWorld(1, 1), Cell{}
+	}
+	world := NewWorld(width, height)
+	for _, c := range cells {
+		world.cells[c.Y-y][c.X-x] = Alive
+	}
+	return world, Cell{X: x, Y: y}
+}
+
+// Step works out the next generation of world on the calling goroutine.
+// p.Turns and p.Threads are ignored.
+func Step(p Params, world *World) *World {
+	//The world is treated as one strip with the rows from the other side of the world as halos
+	height := world.height + 2
+	worldslice := newStrip(p, world.width, height)
+	worldslice.writeRow(0, world.cells[world.height-1])
+	for y, row := range world.cells {
+		worldslice.writeRow(y+1, row)
+	}
+	worldslice.writeRow(height-1, world.cells[0])
+	halo, edge := make([]byte, world.width), make([]byte, world.width)
+	fixStripHalos(worldslice, p.Boundary, true, true, height, halo, edge)
+
+	first, last := make([]byte, height), make([]byte, height)
+	west, east := make([]byte, height), make([]byte, height)
+	worldslice.columns(first, last)
+	p.Boundary.sides(first, last, west, east, 0, world.height, newEdgeColumns(world.cells, 0), 0)
+	worldslice.step(west, east)
+
+	next := NewWorld(world.width, world.height)
+	for y := range next.cells {
+		worldslice.readRow(y+1, next.cells[y])
+	}
+	return next
+}
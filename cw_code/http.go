@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// controlStatus is the body of every response from the control API, describing the game after the request.
+type controlStatus struct {
+	Turn   int  `json:"turn"`
+	Turns  int  `json:"turns"`
+	Alive  int  `json:"alive"`
+	Paused bool `json:"paused"`
+	Done   bool `json:"done"`
+}
+
+// controlAddress returns the address the control API listens on for the -http flag,
+// which is on localhost unless a host is given, as the API has no authentication.
+func controlAddress(address string) string {
+	if strings.HasPrefix(address, ":") {
+		return "localhost" + address
+	}
+	return address
+}
+
+// controlHandler serves the HTTP control API of game, which was started with p and files.
+// It does the same as the keys read by keyboardInputs:
+//
+//	GET  /status           the turn, number of alive cells and whether the game is paused or done
+//	POST /pause            pauses the game, the same as 'p'
+//	POST /resume           carries on after /pause, the same as 'p' again
+//	POST /snapshot         writes out the world as it stands, the same as 's'
+//	POST /quit             calls quit once the reply is sent, the same as 'q', which asks for the world,
+//	                       and a checkpoint if one is given, to be written out before the game is stopped
+//	GET  /world.pgm        the world as it stands as a pgm image
+//	GET  /world.rle        the world as it stands as a pattern file, in any of patternFormats
+//	GET  /                 a page that shows the world live, see viewerPage
+//	GET  /live             a WebSocket streaming the world as it changes, see streamWorld
+//
+// Unbounded engines write out and download the region the live cells lie in rather than the world.
+func controlHandler(p golParams, files fileParams, game *gol.Game, quit func()) http.Handler {
+	status := func() controlStatus {
+		s := controlStatus{Turns: p.Turns, Paused: game.Paused()}
+		s.Turn, s.Alive = game.Count()
+		select {
+		case <-game.Done():
+			s.Done = true
+		default:
+		}
+		return s
+	}
+	reply := func(w http.ResponseWriter, s controlStatus) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s)
+	}
+	//post wraps an action so that it only happens for POST requests, replying with the status afterwards
+	post := func(action func(w http.ResponseWriter)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, "use POST", http.StatusMethodNotAllowed)
+				return
+			}
+			action(w)
+		}
+	}
+
+	//Every viewer streams from the same feed, so they share its snapshots
+	feed := newViewerFeed(game)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(viewerPage))
+	})
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		serveViewer(w, r, feed)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, status())
+	})
+	mux.HandleFunc("/pause", post(func(w http.ResponseWriter) {
+		game.Pause()
+		reply(w, status())
+	}))
+	mux.HandleFunc("/resume", post(func(w http.ResponseWriter) {
+		game.Resume()
+		reply(w, status())
+	}))
+	mux.HandleFunc("/snapshot", post(func(w http.ResponseWriter) {
+		state := game.State()
+		if p.Engine.Unbounded() {
+			writeRegion(p, files, state.Alive())
+		} else {
+			writePgmTurn(p, files, state.World)
+		}
+		s := status()
+		s.Turn = state.Turn
+		reply(w, s)
+	}))
+	mux.HandleFunc("/quit", post(func(w http.ResponseWriter) {
+		reply(w, status())
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		quit()
+	}))
+	for _, format := range append([]string{".pgm"}, patternFormats...) {
+		format := format
+		mux.HandleFunc("/world"+format, func(w http.ResponseWriter, r *http.Request) {
+			state := game.State()
+			world, origin := state.World, cell{}
+			if p.Engine.Unbounded() {
+				world, origin = gol.Region(state.Alive())
+			}
+			w.Header().Set("Content-Disposition", "attachment; filename=\"world-"+strconv.Itoa(state.Turn)+format+"\"")
+			if format == ".pgm" {
+				w.Header().Set("Content-Type", "image/x-portable-graymap")
+				_ = encodePgm(w, world)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_ = writePattern(w, format, world, origin, p.Rule, []string{"#C Turn " + strconv.Itoa(state.Turn)})
+		})
+	}
+	return mux
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"compress/lzw"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// palette gives the colours cells are drawn in, blending from the colour of dead cells
+// to the colour of live ones so that dying cells of Generations rules are drawn in between.
+// The zero palette draws dead cells black and live cells white, like the pgm images.
+type palette struct {
+	dead, alive color.RGBA
+	set         bool
+}
+
+//Replaces the zero palette with black and white
+func (pal palette) orDefault() palette {
+	if !pal.set {
+		return palette{dead: color.RGBA{A: 255}, alive: color.RGBA{R: 255, G: 255, B: 255, A: 255}, set: true}
+	}
+	return pal
+}
+
+//Returns a colour for each grey level a cell can be stored as
+func (pal palette) colours() color.Palette {
+	pal = pal.orDefault()
+	colours := make(color.Palette, 256)
+	blend := func(dead, alive uint8, level int) uint8 {
+		return uint8((int(dead)*(255-level) + int(alive)*level + 127) / 255)
+	}
+	for level := range colours {
+		colours[level] = color.RGBA{
+			R: blend(pal.dead.R, pal.alive.R, level),
+			G: blend(pal.dead.G, pal.alive.G, level),
+			B: blend(pal.dead.B, pal.alive.B, level),
+			A: 255,
+		}
+	}
+	return colours
+}
+
+// String returns the palette as the hex colours of dead and live cells, such as 000000,ffffff.
+func (pal *palette) String() string {
+	p := pal.orDefault()
+	hex := func(c color.RGBA) string {
+		return fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return hex(p.dead) + "," + hex(p.alive)
+}
+
+// Set parses the hex colours of dead and live cells, such as 000000,ffffff, so that a palette can be used as a flag.
+func (pal *palette) Set(s string) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return errors.New("expected the colours of dead and live cells, such as 000000,ffffff")
+	}
+	var colours [2]color.RGBA
+	for i, part := range parts {
+		value, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(part), "#"), 16, 32)
+		if err != nil || len(strings.TrimPrefix(strings.TrimSpace(part), "#")) != 6 {
+			return errors.New("invalid colour " + strconv.Quote(part) + ", expected six hex digits such as ff8800")
+		}
+		colours[i] = color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 255}
+	}
+	*pal = palette{dead: colours[0], alive: colours[1], set: true}
+	return nil
+}
+
+// drawWorld draws world with each cell as a scale x scale square in the colours of pal.
+func drawWorld(world *gol.World, scale int, colours color.Palette) *image.Paletted {
+	if scale < 1 {
+		scale = 1
+	}
+	img := image.NewPaletted(image.Rect(0, 0, world.Width()*scale, world.Height()*scale), colours)
+	for y := 0; y < world.Height(); y++ {
+		row := img.Pix[y*scale*img.Stride : y*scale*img.Stride+img.Rect.Dx()]
+		for x := 0; x < world.Width(); x++ {
+			level := world.Get(x, y)
+			for i := 0; i < scale; i++ {
+				row[x*scale+i] = level
+			}
+		}
+		//The rest of the square is copies of its first row
+		for i := 1; i < scale; i++ {
+			copy(img.Pix[(y*scale+i)*img.Stride:], row)
+		}
+	}
+	return img
+}
+
+// writePng writes world as a png image, with each cell a scale x scale square in the colours of pal.
+func writePng(w io.Writer, world *gol.World, scale int, pal palette) error {
+	return png.Encode(w, drawWorld(world, scale, pal.colours()))
+}
+
+// writePngFile writes world to out/filename.png, drawn as files asks.
+func writePngFile(filename string, world *gol.World, files fileParams) {
+	_ = os.Mkdir("out", os.ModePerm)
+
+	file, ioError := os.Create("out/" + filename + ".png")
+	check(ioError)
+	defer file.Close()
+	check(writePng(file, world, files.scale, files.palette))
+
+	fmt.Println("File", filename+".png", "output done!")
+}
+
+// gifWriter writes an animated gif one frame at a time, so that frames needn't be kept until the last one.
+// Every frame is drawn in the same colours, which are written once as the global colour table.
+type gifWriter struct {
+	w *bufio.Writer
+	//frame is the size of every frame
+	frame image.Rectangle
+}
+
+//The NETSCAPE2.0 application extension, which has the animation loop forever
+var gifLoop = []byte{0x21, 0xff, 0x0b, 'N', 'E', 'T', 'S', 'C', 'A', 'P', 'E', '2', '.', '0', 0x03, 0x01, 0x00, 0x00, 0x00}
+
+// newGifWriter writes the start of a gif of frames the size of bounds in the 256 colours of colours to w.
+func newGifWriter(w io.Writer, bounds image.Rectangle, colours color.Palette) (*gifWriter, error) {
+	if bounds.Dx() > 0xffff || bounds.Dy() > 0xffff {
+		return nil, errors.New("gif frames can't be bigger than 65535x65535")
+	}
+	g := &gifWriter{w: bufio.NewWriter(w), frame: bounds}
+	g.w.WriteString("GIF89a")
+	g.writeSize(bounds.Dx(), bounds.Dy())
+	//A global colour table of 256 colours, with 8 bits for each of red, green and blue
+	g.w.Write([]byte{0xf7, 0, 0})
+	for _, c := range colours {
+		r, gr, b, _ := c.RGBA()
+		g.w.Write([]byte{byte(r >> 8), byte(gr >> 8), byte(b >> 8)})
+	}
+	_, err := g.w.Write(gifLoop)
+	return g, err
+}
+
+func (g *gifWriter) writeSize(width, height int) {
+	g.w.Write([]byte{byte(width), byte(width >> 8), byte(height), byte(height >> 8)})
+}
+
+// write adds img, which must be drawn in the colours the gif was started with, shown for delay hundredths of a second.
+func (g *gifWriter) write(img *image.Paletted, delay int) error {
+	if img.Bounds() != g.frame {
+		return errors.New("every frame of a gif must be the same size")
+	}
+	//The graphic control extension gives the delay, followed by the image descriptor
+	g.w.Write([]byte{0x21, 0xf9, 0x04, 0x00, byte(delay), byte(delay >> 8), 0x00, 0x00, 0x2c, 0, 0, 0, 0})
+	g.writeSize(g.frame.Dx(), g.frame.Dy())
+	//No local colour table, and codes starting at 8 bits for the 256 colours
+	g.w.Write([]byte{0x00, 8})
+	blocks := &gifBlocks{w: g.w}
+	lzwWriter := lzw.NewWriter(blocks, lzw.LSB, 8)
+	for y := 0; y < g.frame.Dy(); y++ {
+		if _, err := lzwWriter.Write(img.Pix[y*img.Stride : y*img.Stride+g.frame.Dx()]); err != nil {
+			return err
+		}
+	}
+	if err := lzwWriter.Close(); err != nil {
+		return err
+	}
+	return blocks.close()
+}
+
+// close ends the gif, without closing the writer it was written to.
+func (g *gifWriter) close() error {
+	g.w.WriteByte(0x3b)
+	return g.w.Flush()
+}
+
+//Splits the image data of a gif into the sub-blocks of up to 255 bytes it is stored in
+type gifBlocks struct {
+	w     *bufio.Writer
+	block [255]byte
+	n     int
+}
+
+func (b *gifBlocks) Write(data []byte) (int, error) {
+	written := len(data)
+	for len(data) > 0 {
+		copied := copy(b.block[b.n:], data)
+		b.n += copied
+		data = data[copied:]
+		if b.n == len(b.block) {
+			if err := b.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (b *gifBlocks) flush() error {
+	if b.n == 0 {
+		return nil
+	}
+	b.w.WriteByte(byte(b.n))
+	_, err := b.w.Write(b.block[:b.n])
+	b.n = 0
+	return err
+}
+
+//Writes out the last sub-block followed by the empty block that ends the image data
+func (b *gifBlocks) close() error {
+	if err := b.flush(); err != nil {
+		return err
+	}
+	return b.w.WriteByte(0)
+}
+
+// recordGif draws every frame of a game into an animated gif at path, writing each one out as it arrives
+// so that none are kept once they have been written. Frames are shown for delay hundredths of a second each.
+// The frames are read until the channel is closed even if the gif can't be written, as the game waits for them.
+func recordGif(frames <-chan gol.Frame, path string, delay int, files fileParams) error {
+	colours := files.palette.colours()
+	var file *os.File
+	var animation *gifWriter
+	var err error
+	for frame := range frames {
+		if err != nil {
+			continue
+		}
+		img := drawWorld(frame.World, files.scale, colours)
+		if file == nil {
+			if file, err = os.Create(path); err != nil {
+				continue
+			}
+			animation, err = newGifWriter(file, img.Bounds(), colours)
+		}
+		if err == nil {
+			err = animation.write(img, delay)
+		}
+	}
+	if file == nil {
+		if err != nil {
+			return err
+		}
+		return errors.New("no frames were recorded for " + path)
+	}
+	if err == nil {
+		err = animation.close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("File", path, "output done!")
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// readInput reads the netpbm image or pattern file at path, as chosen by its extension.
+// Pattern files give a world the size of the pattern.
+// threshold is the grey level at which pixels of greymaps become live cells, and rule gives the states
+// grey levels are snapped to without one, see readNetpbm.
+func readInput(path string, threshold int, rule gol.Rule) (*gol.World, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	image := false
+	for _, netpbm := range netpbmExtensions {
+		image = image || ext == netpbm
+	}
+	var format string
+	if !image {
+		var err error
+		if format, err = patternFormat(path); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if image {
+		world, err := readNetpbm(file, threshold, rule)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return world, nil
+	}
+
+	pat, err := decodePattern(file, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if pat.width < 1 || pat.height < 1 {
+		return nil, fmt.Errorf("%s: pattern is empty", path)
+	}
+	world := gol.NewWorld(pat.width, pat.height)
+	pat.place(world, cell{})
+	return world, nil
+}
+
+// fitWorld copies world into a new world of the given size with its top left corner at offset,
+// padding it with dead cells or cropping it to fit. A width or height of 0 means just big enough to hold it.
+func fitWorld(world *gol.World, width, height int, offset cell) *gol.World {
+	if width == 0 {
+		width = world.Width() + offset.X
+	}
+	if height == 0 {
+		height = world.Height() + offset.Y
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	fitted := gol.NewWorld(width, height)
+	for y := 0; y < world.Height(); y++ {
+		for x := 0; x < world.Width(); x++ {
+			fx, fy := x+offset.X, y+offset.Y
+			if fx >= 0 && fx < width && fy >= 0 && fy < height {
+				fitted.Set(fx, fy, world.Get(x, y))
+			}
+		}
+	}
+	return fitted
+}
+
+// inputSize returns the size of the world files.input makes when padded or cropped to width x height,
+// so that it is known before the game starts.
+func inputSize(files fileParams, rule gol.Rule, width, height int) (int, int, error) {
+	world, err := readInput(files.input, files.threshold, rule)
+	if err != nil {
+		return 0, 0, err
+	}
+	world = fitWorld(world, width, height, files.offset)
+	return world.Width(), world.Height(), nil
+}
+
+// readInputImage reads the file named on the filename channel, pads or crops it to the size in p
+// and sends it to the distributor, or sends the error if the file can't be read.
+func readInputImage(p golParams, files fileParams, i ioChans) {
+	filename := <-i.distributor.filename
+	world, err := readInput(filename, files.threshold, p.Rule)
+	if err != nil {
+		i.distributor.inputError <- err
+		return
+	}
+	i.distributor.worldInput <- fitWorld(world, p.ImageWidth, p.ImageHeight, files.offset)
+
+	fmt.Println("File", filename, "input done!")
+}
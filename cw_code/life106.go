@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+//The first line of every Life 1.06 file
+const life106Header = "#Life 1.06"
+
+// readLife106 reads a pattern in Life 1.06 format, which lists the x and y of one live cell on each line.
+// The cells are moved so that the pattern's top left corner is at 0, 0.
+func readLife106(r io.Reader) (*pattern, error) {
+	var pat pattern
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != life106Header {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("Life 1.06 pattern should start with " + strconv.Quote(life106Header))
+	}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			pat.comments = append(pat.comments, line)
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.New("invalid Life 1.06 line " + strconv.Quote(line))
+		}
+		x, errX := strconv.Atoi(fields[0])
+		y, errY := strconv.Atoi(fields[1])
+		if errX != nil || errY != nil {
+			return nil, errors.New("invalid Life 1.06 line " + strconv.Quote(line))
+		}
+		pat.cells = append(pat.cells, cell{X: x, Y: y})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	x, y, width, height := gol.Bounds(pat.cells)
+	for i := range pat.cells {
+		pat.cells[i].X -= x
+		pat.cells[i].Y -= y
+	}
+	pat.width, pat.height = width, height
+	return &pat, nil
+}
+
+// writeLife106 writes cells in Life 1.06 format, one cell to a line.
+func writeLife106(w io.Writer, cells []cell) error {
+	out := bufio.NewWriter(w)
+	out.WriteString(life106Header + "\n")
+	for _, c := range cells {
+		out.WriteString(strconv.Itoa(c.X) + " " + strconv.Itoa(c.Y) + "\n")
+	}
+	return out.Flush()
+}
@@ -3,18 +3,19 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
 )
 
 // golParams provides the details of how to run the Game of Life and which image to load.
-type golParams struct {
-	turns       int
-	threads     int
-	imageWidth  int
-	imageHeight int
-}
+type golParams = gol.Params
 
 // ioCommand allows requesting behaviour from the io (pgm) goroutine.
 type ioCommand uint8
@@ -31,19 +32,7 @@ const (
 )
 
 // cell is used as the return type for the testing framework.
-type cell struct {
-	x, y int
-}
-
-//Defines channels that the keyboard inputs use to communicate to the workers
-type keyChans struct {
-	startSend    chan bool
-	finishedSend chan bool
-	currentCells chan cell
-	turnsPrinted chan bool
-	printTurns   chan bool
-	pause        *sync.WaitGroup
-}
+type cell = gol.Cell
 
 // distributorToIo defines all chans that the distributor goroutine will have to communicate with the io goroutine.
 // Note the restrictions on chans being send-only or receive-only to prevent bugs.
@@ -51,20 +40,13 @@ type distributorToIo struct {
 	command chan<- ioCommand
 	idle    <-chan bool
 
-	filename chan<- string
-	inputVal <-chan uint8
-
-	//aliveOutput sends cells from distributer to pgm
-	aliveOutput    chan []cell
-	periodicOutput chan bool
-	stop           *sync.WaitGroup
-	threadsyncin   chan bool
-	threadsyncout  chan byte
+	filename   chan<- string
+	worldInput <-chan *gol.World
+	inputError <-chan error
 
-	periodicNumber chan int
-	numberLogged   chan byte
-	turnsync       chan int
-	turnsback      chan int
+	//worldOutput sends the final world from distributer to pgm
+	worldOutput chan<- *gol.World
+	stop        *sync.WaitGroup
 }
 
 // ioToDistributor defines all chans that the io goroutine will have to communicate with the distributor goroutine.
@@ -73,10 +55,11 @@ type ioToDistributor struct {
 	command <-chan ioCommand
 	idle    chan<- bool
 
-	filename <-chan string
-	inputVal chan<- uint8
+	filename   <-chan string
+	worldInput chan<- *gol.World
+	inputError chan<- error
 
-	aliveOutput chan []cell
+	worldOutput <-chan *gol.World
 	stop        *sync.WaitGroup
 }
 
@@ -90,91 +73,170 @@ type ioChans struct {
 	distributor ioToDistributor
 }
 
-func collateBoard(dChans distributorChans, p golParams, k keyChans) []cell {
-	var receivedFrom = 0
-	for {
-		<-k.finishedSend
-		receivedFrom++
-		if receivedFrom == p.threads {
-			break
-		}
-	}
-
-	var currentAlive []cell
-	finishedloop := false
-	for {
+// keyboardInputs carries out the keys pressed while game runs, handing any it doesn't use on to viewKeys if it isn't nil.
+// 'q' calls requestQuit, which leaves quitting to the goroutine running the game.
+func keyboardInputs(p golParams, files fileParams, keyChan <-chan rune, game *gol.Game, viewKeys chan<- rune, requestQuit func()) {
+	//forward hands a key to the terminal view, dropping it if the view is busy
+	forward := func(key rune) {
 		select {
-		case c := <-k.currentCells:
-			currentAlive = append(currentAlive, c)
-			break
+		case viewKeys <- key:
 		default:
-			finishedloop = true
-			break
-		}
-		if finishedloop {
-			break
 		}
 	}
-	return currentAlive
-}
-
-func keyboardInputs(p golParams, keyChan <-chan rune, dChans distributorChans, kChans keyChans) {
-	paused := false
 	for {
 		time.Sleep(17 * time.Millisecond)
 		select {
 		case key := <-keyChan:
 			switch key {
 			case 's':
-				kChans.startSend <- true
-				currentAlive := collateBoard(dChans, p, kChans)
-				go writePgmTurn(p, currentAlive)
+				if p.Engine.Unbounded() {
+					go writeRegion(p, files, game.Snapshot())
+				} else {
+					go writePgmTurn(p, files, game.SnapshotWorld())
+				}
 			case 'p':
-				kChans.printTurns <- true
-				<-kChans.turnsPrinted
-				kChans.pause.Add(1)
-				fmt.Println("Paused")
-
-				//Can continue on the next p press
-				for {
-					select {
-					case key := <-keyChan:
-						switch key {
-						case 'p':
-							kChans.pause.Done()
-							fmt.Println("Continuing")
-							paused = true
-							break
-						}
-					}
-					if paused {
-						paused = false
-						break
-					}
+				//The game may already have been paused over HTTP, in which case p resumes it
+				if turn, ok := game.Pause(); ok {
+					fmt.Println("Turn: ", turn)
+					fmt.Println("Paused")
+				} else if game.Resume() {
+					fmt.Println("Continuing")
 				}
 			case 'q':
-				kChans.startSend <- true
-				currentAlive := collateBoard(dChans, p, kChans)
-				kChans.pause.Add(1)
-				writePgmTurn(p, currentAlive)
-				StopControlServer()
-
-				os.Exit(0)
+				requestQuit()
+				return
+			default:
+				forward(key)
 			}
+		case <-game.Done():
+			return
 		default:
 			//do nothing
 		}
 	}
 }
 
+// quit writes out the world as it stands, along with a checkpoint if files.checkpoint is given,
+// and leaves the game paused so that it can be returned from. It returns the state written out.
+func quit(p golParams, files fileParams, game *gol.Game) gol.State {
+	current := game.State()
+	game.Pause()
+	if p.Engine.Unbounded() {
+		writeRegion(p, files, current.Alive())
+	} else {
+		writePgmTurn(p, files, current.World)
+	}
+	if files.checkpoint != "" {
+		if err := writeCheckpoint(files.checkpoint, p, files.soup, current); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println("Checkpoint at turn", current.Turn, "written to", files.checkpoint)
+		}
+	}
+	return current
+}
+
+//Returns a channel passing on frames until they run out or stop is closed, and then closed,
+//so that a gif can be finished off with the frames it has when the game is quit
+func framesUntil(frames <-chan gol.Frame, stop <-chan bool) <-chan gol.Frame {
+	out := make(chan gol.Frame)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case frame, ok := <-frames:
+				if !ok {
+					return
+				}
+				select {
+				case out <- frame:
+				case <-stop:
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+//Is framesUntil for stats
+func statsUntil(stats <-chan gol.Stats, stop <-chan bool) <-chan gol.Stats {
+	out := make(chan gol.Stats)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case s, ok := <-stats:
+				if !ok {
+					return
+				}
+				select {
+				case out <- s:
+				case <-stop:
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// readWorld asks the io goroutine for the image matching the size in p, or the file in files.input,
+// and returns the starting world, padded or cropped to the size in p.
+// If files.soup is on, a soup the size in p is generated instead.
+func readWorld(p golParams, files fileParams, d distributorChans) (*gol.World, error) {
+	if files.soup.on {
+		fmt.Println("Soup with seed", files.soup.seed)
+		return generateSoup(p.ImageWidth, p.ImageHeight, files.soup)
+	}
+
+	// Request the io goroutine to read in the image with the given filename.
+	d.io.command <- ioInput
+	if files.input != "" {
+		d.io.filename <- files.input
+	} else {
+		d.io.filename <- "images/" + strings.Join([]string{strconv.Itoa(p.ImageWidth), strconv.Itoa(p.ImageHeight)}, "x") + ".pgm"
+	}
+
+	var world *gol.World
+	select {
+	case world = <-d.io.worldInput:
+	case err := <-d.io.inputError:
+		return nil, err
+	}
+	for _, c := range world.Alive() {
+		fmt.Println("Alive cell at", c.X, c.Y)
+	}
+	return world, nil
+}
+
 // gameOfLife is the function called by the testing framework.
 // It makes some channels and starts relevant goroutines.
 // It places the created channels in the relevant structs.
-// It returns an array of alive cells returned by the distributor.
+// It returns an array of alive cells returned by the engine.
 func gameOfLife(p golParams, keyChan <-chan rune) []cell {
+	alive, err := gameOfLifeFiles(p, fileParams{}, keyChan)
+	check(err)
+	return alive
+}
+
+// gameOfLifeFiles is gameOfLife starting from the world described by files.
+// If files.input is given, p.ImageWidth and p.ImageHeight pad or crop it, or are taken from it if they are 0.
+// If files.resume is given, the game carries on from that checkpoint with the params it was taken with instead.
+// The final alive cells are written out as a pattern file in files.format as well as a pgm file,
+// with RLE used if no format is given.
+// It returns an error if the input can't be read.
+func gameOfLifeFiles(p golParams, files fileParams, keyChan <-chan rune) ([]cell, error) {
+	if files.format == "" {
+		files.format = ".rle"
+	}
+
 	var dChans distributorChans
 	var ioChans ioChans
-	var keyChans keyChans
 
 	ioCommand := make(chan ioCommand)
 	dChans.io.command = ioCommand
@@ -188,74 +250,186 @@ func gameOfLife(p golParams, keyChan <-chan rune) []cell {
 	dChans.io.filename = ioFilename
 	ioChans.distributor.filename = ioFilename
 
-	inputVal := make(chan uint8)
-	dChans.io.inputVal = inputVal
-	ioChans.distributor.inputVal = inputVal
-
-	periodicOutput := make(chan bool, p.threads)
-	dChans.io.periodicOutput = periodicOutput
-	periodicNumber := make(chan int, p.threads*p.threads*p.threads)
-	dChans.io.periodicNumber = periodicNumber
-	numberLogged := make(chan byte, p.threads*p.threads*p.threads)
-	dChans.io.numberLogged = numberLogged
-	turnsync := make(chan int)
-	dChans.io.turnsync = turnsync
-	turnsback := make(chan int)
-	dChans.io.turnsback = turnsback
+	worldInput := make(chan *gol.World)
+	dChans.io.worldInput = worldInput
+	ioChans.distributor.worldInput = worldInput
+
+	inputError := make(chan error)
+	dChans.io.inputError = inputError
+	ioChans.distributor.inputError = inputError
 
 	var stop sync.WaitGroup
 	dChans.io.stop = &stop
 	ioChans.distributor.stop = &stop
-	threadsyncin := make(chan bool, p.threads)
-	dChans.io.threadsyncin = threadsyncin
-
-	threadsyncout := make(chan byte, p.threads)
-	dChans.io.threadsyncout = threadsyncout
 
-	aliveOutput := make(chan []cell)
-	dChans.io.aliveOutput = aliveOutput
-	ioChans.distributor.aliveOutput = aliveOutput
+	worldOutput := make(chan *gol.World)
+	dChans.io.worldOutput = worldOutput
+	ioChans.distributor.worldOutput = worldOutput
 
-	startSend := make(chan bool)
-	keyChans.startSend = startSend
+	stop.Add(1)
+	go pgmIo(p, files, ioChans)
 
-	finishedSend := make(chan bool, p.threads)
-	keyChans.finishedSend = finishedSend
+	var state gol.State
+	if files.resume != "" {
+		var err error
+		if p, state, files.soup, err = readCheckpoint(files.resume); err != nil {
+			return nil, err
+		}
+	} else {
+		world, err := readWorld(p, files, dChans)
+		if err != nil {
+			return nil, err
+		}
+		state.World = world
+	}
+	p.ImageWidth, p.ImageHeight = state.World.Width(), state.World.Height()
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
 
-	currentCells := make(chan cell, p.imageHeight*p.imageWidth)
-	keyChans.currentCells = currentCells
+	if len(files.workers) > 0 {
+		return gameOfLifeCluster(p, files, state, dChans)
+	}
 
-	printTurns := make(chan bool)
-	turnsPrinted := make(chan bool, 1)
+	//The gif is recorded from frames the workers send every files.gifEvery turns
+	gifDone := make(chan error, 1)
+	if files.gif != "" {
+		p.FrameEvery = files.gifEvery
+		if p.FrameEvery < 1 {
+			p.FrameEvery = 1
+		}
+	}
 
-	keyChans.printTurns = printTurns
-	keyChans.turnsPrinted = turnsPrinted
+	if files.stats != "" {
+		p.StatsEvery = files.statsEvery
+		if p.StatsEvery < 1 {
+			p.StatsEvery = 1
+		}
+	}
 
-	var pause sync.WaitGroup
-	keyChans.pause = &pause
+	game := gol.Resume(p, state)
+	//Quitting is asked for by 'q' and the control API, and carried out here once the game is stopped,
+	//so that the gif and stats are finished off and everything is cleaned up on the way out
+	quitting := make(chan bool, 1)
+	requestQuit := func() {
+		select {
+		case quitting <- true:
+		default:
+		}
+	}
+	stopRecording := make(chan bool)
+	statsDone := make(chan error, 1)
+	if files.stats != "" {
+		go func() {
+			statsDone <- recordStats(statsUntil(game.Stats(), stopRecording), files.stats)
+		}()
+	} else {
+		statsDone <- nil
+	}
+	if files.gif != "" {
+		go func() {
+			gifDone <- recordGif(framesUntil(game.Frames(), stopRecording), files.gif, 10, files)
+		}()
+	} else {
+		gifDone <- nil
+	}
+	if files.http != "" {
+		listener, err := net.Listen("tcp", controlAddress(files.http))
+		if err != nil {
+			return nil, err
+		}
+		defer listener.Close()
+		fmt.Println("Control API listening on", listener.Addr())
+		go http.Serve(listener, controlHandler(p, files, game, requestQuit))
+	}
+	//The terminal view shows the number of alive cells itself
+	var viewKeys chan rune
+	if files.view != viewOff && !files.headless {
+		viewKeys = make(chan rune, 16)
+		go viewWorld(game, files.view, viewKeys)
+	} else {
+		go periodic(p, game)
+	}
+	go saveCheckpoints(p, files, game)
+	go keyboardInputs(p, files, keyChan, game, viewKeys, requestQuit)
+
+	select {
+	case <-game.Done():
+	case <-quitting:
+		current := quit(p, files, game)
+		close(stopRecording)
+		if err := <-gifDone; err != nil {
+			return nil, err
+		}
+		if err := <-statsDone; err != nil {
+			return nil, err
+		}
+		return current.Alive(), nil
+	}
+	final := game.Result()
+	filename := worldName(p)
+	comments := []string{"#C After " + strconv.Itoa(p.Turns) + " turns"}
+	if p.DetectCycles || p.StopOnCycle {
+		cycle := game.Cycle()
+		fmt.Println("Cycle:", cycle)
+		comments = append(comments, "#C "+cycle.String())
+	}
+	var origin cell
+	if p.Engine.Unbounded() {
+		//Unbounded engines write out the region the live cells ended up in rather than the starting world
+		final, origin = gol.Region(game.Wait())
+		filename = regionName(final, origin)
+	}
 
-	aliveCells := make(chan []cell)
-	go periodic(dChans, p)
-	go distributor(p, dChans, aliveCells, keyChans)
+	writeFinal(p, files, dChans, filename, final, origin, comments)
+	if err := <-gifDone; err != nil {
+		return nil, err
+	}
+	if err := <-statsDone; err != nil {
+		return nil, err
+	}
+	return game.Wait(), nil
+}
 
-	go keyboardInputs(p, keyChan, dChans, keyChans)
-	stop.Add(1)
-	go pgmIo(p, ioChans)
+//Names the files the final world is written to by its size
+func worldName(p golParams) string {
+	return strings.Join([]string{strconv.Itoa(p.ImageWidth), strconv.Itoa(p.ImageHeight)}, "x")
+}
 
-	alive := <-aliveCells
-	dChans.io.stop.Wait()
-	return alive
+// writeFinal has the io goroutine write the final world to filename as a pgm image,
+// then writes it as a pattern file and a png if files.png is set.
+// origin is where the world's top left corner lies on the plane, and comments go at the top of the pattern file.
+func writeFinal(p golParams, files fileParams, d distributorChans, filename string, final *gol.World, origin cell, comments []string) {
+	// Make sure that the Io has finished any output before exiting.
+	d.io.command <- ioCheckIdle
+	<-d.io.idle
+
+	// Telling pgm.go to start the write function
+	d.io.command <- ioOutput
+	d.io.filename <- filename
+	d.io.worldOutput <- final
+
+	d.io.stop.Wait()
+	writePatternFile(filename, files.format, final, origin, p.Rule, comments)
+	if files.png {
+		writePngFile(filename, final, files)
+	}
 }
 
-func periodic(d distributorChans, p golParams) {
+// periodic prints the number of alive cells every 2 seconds until the game finishes.
+// Unbounded engines also print the bounding box of the live cells.
+func periodic(p golParams, game *gol.Game) {
 	for {
-		d.io.periodicOutput <- true
-		number := 0
-		for i := 0; i < p.threads; i++ {
-			number += <-d.io.periodicNumber
+		fmt.Println("Cells alive: ", game.AliveCount())
+		if p.Engine.Unbounded() {
+			x, y, width, height := gol.Bounds(game.Snapshot())
+			fmt.Println("Bounding box: ", width, "x", height, "at", x, y)
+		}
+		select {
+		case <-game.Done():
+			return
+		case <-time.After(2 * time.Second):
 		}
-		fmt.Println("Cells alive: ", number)
-		time.Sleep(2 * time.Second)
 	}
 }
 
@@ -265,30 +439,319 @@ func main() {
 	var params golParams
 
 	flag.IntVar(
-		&params.threads,
+		&params.Threads,
 		"t",
 		8,
 		"Specify the number of worker threads to use. Defaults to 8.")
 
 	flag.IntVar(
-		&params.imageWidth,
+		&params.ImageWidth,
 		"w",
-		512,
-		"Specify the width of the image. Defaults to 512.")
+		0,
+		"Specify the width of the image, which pads or crops the -input file. Defaults to the width of the -input file, or 512.")
 
 	flag.IntVar(
-		&params.imageHeight,
+		&params.ImageHeight,
 		"h",
-		512,
-		"Specify the height of the image. Defaults to 512.")
+		0,
+		"Specify the height of the image, which pads or crops the -input file. Defaults to the height of the -input file, or 512.")
+
+	flag.Var(
+		&params.Rule,
+		"rule",
+		"Specify the rule in B/S notation, e.g. B36/S23 for HighLife, or B/S/C for Generations rules, e.g. B2/S/C3 for Brian's Brain. Defaults to B3/S23.")
+
+	flag.Var(
+		&params.Boundary,
+		"boundary",
+		"Specify what lies beyond the edges of the world: torus, dead, klein, cross or mirror. Defaults to torus.")
+
+	flag.Var(
+		&params.Backend,
+		"backend",
+		"Specify how workers store the world: byte, or bits for 64 cells to a word. Defaults to byte.")
+
+	flag.BoolVar(
+		&params.Shared,
+		"shared",
+		false,
+		"Have the workers share one copy of the world in memory, waiting for each other at a barrier every turn, instead of sending each other the rows on the edges of their strips.")
+
+	flag.Var(
+		&params.Engine,
+		"engine",
+		"Specify the engine: workers, hashlife for very long runs on an infinite plane, or sparse for patterns that grow without limit. Defaults to workers.")
+
+	flag.IntVar(
+		&params.Turns,
+		"turns",
+		500000,
+		"Specify the number of turns to simulate. Defaults to 500000.")
+
+	flag.IntVar(
+		&params.MaxNodes,
+		"maxnodes",
+		0,
+		"Specify how many nodes the hashlife engine keeps before collecting garbage. Defaults to 4194304.")
+
+	flag.BoolVar(
+		&params.DetectCycles,
+		"cycles",
+		false,
+		"Look for the world dying out, settling down, or repeating itself with some period, possibly moved, and print what was found at the end.")
+
+	flag.BoolVar(
+		&params.StopOnCycle,
+		"stop-on-cycle",
+		false,
+		"Stop as soon as the final world is known from a cycle found by -cycles, which implies -cycles.")
+
+	var files fileParams
+
+	flag.StringVar(
+		&files.input,
+		"input",
+		"",
+		"Specify the path of a file to start from, chosen by its extension: .pgm, .pbm or .pnm for netpbm images, .rle, .cells, or .lif for Life 1.06. Defaults to the pgm image in images/ of the size given by -w and -h.")
+
+	flag.Var(
+		(*cellValue)(&files.offset),
+		"offset",
+		"Specify where the top left corner of the -input file goes in the world, as x,y. Defaults to 0,0.")
+
+	flag.IntVar(
+		&files.threshold,
+		"threshold",
+		0,
+		"Specify the grey level from 1 to 255 at which pixels of -input images become live cells. Defaults to 0, which gives each pixel the state of the -rule nearest to its grey level.")
+
+	flag.StringVar(
+		&files.format,
+		"format",
+		"rle",
+		"Specify the format the alive cells are written out in as well as pgm: rle, cells, or lif for Life 1.06. Defaults to rle.")
+
+	flag.BoolVar(
+		&files.png,
+		"png",
+		false,
+		"Write png images as well as pgm images, which can be opened in a browser.")
+
+	flag.StringVar(
+		&files.gif,
+		"gif",
+		"",
+		"Specify the path of an animated gif to record the game in.")
+
+	flag.IntVar(
+		&files.gifEvery,
+		"gif-every",
+		1,
+		"Specify how many turns apart the frames of the -gif are. Defaults to 1.")
+
+	flag.IntVar(
+		&files.scale,
+		"scale",
+		1,
+		"Specify the width and height in pixels of each cell in png and gif images. Defaults to 1.")
+
+	flag.Var(
+		&files.palette,
+		"palette",
+		"Specify the colours of dead and live cells in png and gif images as hex, such as 000000,ffffff. Defaults to black and white.")
+
+	flag.StringVar(
+		&files.checkpoint,
+		"checkpoint",
+		"",
+		"Specify the path of a checkpoint to write every -checkpoint-every and on 'q', which -resume carries on from. Defaults to none, which writes no checkpoints.")
+
+	flag.DurationVar(
+		&files.checkpointEvery,
+		"checkpoint-every",
+		time.Minute,
+		"Specify how often the -checkpoint is written, such as 30s or 5m, or 0 to only write it on 'q'. Defaults to 1m.")
+
+	flag.StringVar(
+		&files.resume,
+		"resume",
+		"",
+		"Specify the path of a checkpoint to carry on from, with the turns, threads, rule, boundary, backend and engine it was taken with.")
+
+	flag.BoolVar(
+		&files.soup.on,
+		"soup",
+		false,
+		"Start from a random soup instead of an image or -input file.")
+
+	flag.Float64Var(
+		&files.soup.density,
+		"density",
+		0.5,
+		"Specify the chance of each cell of the -soup being alive, from 0 to 1. Defaults to 0.5.")
+
+	flag.Int64Var(
+		&files.soup.seed,
+		"seed",
+		0,
+		"Specify the seed of the -soup, so that it can be generated again. Defaults to one taken from the clock, which is printed.")
+
+	flag.Var(
+		&files.soup.symmetry,
+		"symmetry",
+		"Specify the symmetry of the -soup: C1, C2, C4 or D8. C4 and D8 need a square soup. Defaults to C1, which has none.")
+
+	flag.Var(
+		&files.soup.rect,
+		"soup-rect",
+		"Specify the part of the world the -soup fills as x,y,width,height, leaving the rest dead. Defaults to the whole world.")
+
+	flag.StringVar(
+		&files.stats,
+		"stats",
+		"",
+		"Specify the path of a .csv or .jsonl file to write the population, births, deaths, bounding box and population of each worker to.")
+
+	flag.IntVar(
+		&files.statsEvery,
+		"stats-every",
+		1,
+		"Specify how many turns apart the -stats are. Defaults to 1.")
+
+	flag.StringVar(
+		&files.http,
+		"http",
+		"",
+		"Specify the address to serve the HTTP control API and live viewer on, such as :8080, which only listens on localhost. "+
+			"Give a host such as 0.0.0.0:8080 to let anyone on the network control the game. See http.go for its endpoints.")
+
+	flag.BoolVar(
+		&files.headless,
+		"headless",
+		false,
+		"Don't read keys from the terminal, for running without one. The game can still be controlled with -http, and from another terminal with -controller.")
+
+	flag.Var(
+		&files.view,
+		"view",
+		"Specify how the world is drawn in the terminal as it runs: off, half or braille, which fit two or eight cells in each character. Defaults to off.")
+
+	flag.Var(
+		(*addressList)(&files.workers),
+		"workers",
+		"Specify the addresses of worker processes started with -worker to spread the world over, separated by commas, such as localhost:8030,localhost:8031.")
+
+	flag.Var(
+		(*addressList)(&files.spares),
+		"spares",
+		"Specify the addresses of spare worker processes, separated by commas, that take over from any of the -workers that fail.")
+
+	var workerAddress string
+	flag.StringVar(
+		&workerAddress,
+		"worker",
+		"",
+		"Run as a worker process listening on the given address, such as :8030, instead of running a game.")
+
+	var controllerAddress string
+	flag.StringVar(
+		&controllerAddress,
+		"controller",
+		"",
+		"Control a game started elsewhere with -http, such as one left running with -headless, instead of running one. "+
+			"Keys are s, p, q to disconnect and leave the game running, and k to shut it down.")
 
 	flag.Parse()
 
-	params.turns = 500000
+	if workerAddress != "" {
+		if err := serveWorker(workerAddress); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if controllerAddress != "" {
+		if err := runController(controllerAddress); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(files.workers) > 0 || len(files.spares) > 0 {
+		if err := checkClusterFiles(files); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
 
-	startControlServer(params)
+	if files.soup.on {
+		if files.input != "" {
+			fmt.Println("-soup and -input can't be used together")
+			os.Exit(2)
+		}
+		if files.soup.seed == 0 {
+			files.soup.seed = time.Now().UnixNano()
+		}
+	}
+	if files.stats != "" {
+		if err := checkStatsPath(files.stats); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+	if params.StopOnCycle && files.gif != "" {
+		fmt.Println("-gif needs every turn to be run, so it can't be used with -stop-on-cycle")
+		os.Exit(2)
+	}
+	if files.threshold < 0 || files.threshold > 255 {
+		fmt.Println("the threshold must be between 0 and 255")
+		os.Exit(2)
+	}
+	if files.resume != "" {
+		//The checkpoint's params are read up front so that they can be shown before the game starts
+		p, _, _, err := readCheckpoint(files.resume)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		params = p
+	} else if files.input == "" {
+		if params.ImageWidth == 0 {
+			params.ImageWidth = 512
+		}
+		if params.ImageHeight == 0 {
+			params.ImageHeight = 512
+		}
+	} else {
+		//The size is worked out up front so that it can be shown before the game starts
+		width, height, err := inputSize(files, params.Rule, params.ImageWidth, params.ImageHeight)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		params.ImageWidth, params.ImageHeight = width, height
+	}
+
+	if err := params.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	format, err := patternFormat("." + strings.TrimPrefix(files.format, "."))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	files.format = format
+
+	startControlServer(params, files.headless)
 	keyChannel := make(chan rune, 60)
-	go getKeyboardCommand(keyChannel)
-	gameOfLife(params, keyChannel)
+	if !files.headless {
+		go getKeyboardCommand(keyChannel)
+	}
+	_, err = gameOfLifeFiles(params, files, keyChannel)
 	StopControlServer()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
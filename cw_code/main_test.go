@@ -1,9 +1,32 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/nsf/termbox-go"
 	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/cluster"
+	"uk.ac.bris.cs/gameoflife/gol"
 )
 
 func Test(t *testing.T) {
@@ -17,299 +40,299 @@ func Test(t *testing.T) {
 	}{
 		{"16x16x2-0", args{
 			p: golParams{
-				turns:       0,
-				threads:     2,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       0,
+				Threads:     2,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 4, y: 5},
-				{x: 5, y: 6},
-				{x: 3, y: 7},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
+				{X: 4, Y: 5},
+				{X: 5, Y: 6},
+				{X: 3, Y: 7},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
 			},
 		}},
 
 		{"16x16x4-0", args{
 			p: golParams{
-				turns:       0,
-				threads:     4,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       0,
+				Threads:     4,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 4, y: 5},
-				{x: 5, y: 6},
-				{x: 3, y: 7},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
+				{X: 4, Y: 5},
+				{X: 5, Y: 6},
+				{X: 3, Y: 7},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
 			},
 		}},
 
 		{"16x16x8-0", args{
 			p: golParams{
-				turns:       0,
-				threads:     8,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       0,
+				Threads:     8,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 4, y: 5},
-				{x: 5, y: 6},
-				{x: 3, y: 7},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
+				{X: 4, Y: 5},
+				{X: 5, Y: 6},
+				{X: 3, Y: 7},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
 			},
 		}},
 
 		{"16x16x2-1", args{
 			p: golParams{
-				turns:       1,
-				threads:     2,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       1,
+				Threads:     2,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 3, y: 6},
-				{x: 5, y: 6},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
-				{x: 4, y: 8},
+				{X: 3, Y: 6},
+				{X: 5, Y: 6},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
+				{X: 4, Y: 8},
 			},
 		}},
 
 		{"16x16x4-1", args{
 			p: golParams{
-				turns:       1,
-				threads:     4,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       1,
+				Threads:     4,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 3, y: 6},
-				{x: 5, y: 6},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
-				{x: 4, y: 8},
+				{X: 3, Y: 6},
+				{X: 5, Y: 6},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
+				{X: 4, Y: 8},
 			},
 		}},
 
 		{"16x16x8-1", args{
 			p: golParams{
-				turns:       1,
-				threads:     8,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       1,
+				Threads:     8,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 3, y: 6},
-				{x: 5, y: 6},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
-				{x: 4, y: 8},
+				{X: 3, Y: 6},
+				{X: 5, Y: 6},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
+				{X: 4, Y: 8},
 			},
 		}},
 
 		{"16x16x2-100", args{
 			p: golParams{
-				turns:       100,
-				threads:     2,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       100,
+				Threads:     2,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 12, y: 0},
-				{x: 13, y: 0},
-				{x: 14, y: 0},
-				{x: 13, y: 14},
-				{x: 14, y: 15},
+				{X: 12, Y: 0},
+				{X: 13, Y: 0},
+				{X: 14, Y: 0},
+				{X: 13, Y: 14},
+				{X: 14, Y: 15},
 			},
 		}},
 
 		{"16x16x4-100", args{
 			p: golParams{
-				turns:       100,
-				threads:     4,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       100,
+				Threads:     4,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 12, y: 0},
-				{x: 13, y: 0},
-				{x: 14, y: 0},
-				{x: 13, y: 14},
-				{x: 14, y: 15},
+				{X: 12, Y: 0},
+				{X: 13, Y: 0},
+				{X: 14, Y: 0},
+				{X: 13, Y: 14},
+				{X: 14, Y: 15},
 			},
 		}},
 
 		{"16x16x8-100", args{
 			p: golParams{
-				turns:       100,
-				threads:     8,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       100,
+				Threads:     8,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 12, y: 0},
-				{x: 13, y: 0},
-				{x: 14, y: 0},
-				{x: 13, y: 14},
-				{x: 14, y: 15},
+				{X: 12, Y: 0},
+				{X: 13, Y: 0},
+				{X: 14, Y: 0},
+				{X: 13, Y: 14},
+				{X: 14, Y: 15},
 			},
 		}},
 		//Stage 3 tests
 		{"16x16x6-0", args{
 			p: golParams{
-				turns:       0,
-				threads:     6,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       0,
+				Threads:     6,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 4, y: 5},
-				{x: 5, y: 6},
-				{x: 3, y: 7},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
+				{X: 4, Y: 5},
+				{X: 5, Y: 6},
+				{X: 3, Y: 7},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
 			},
 		}},
 
 		{"16x16x6-1", args{
 			p: golParams{
-				turns:       1,
-				threads:     6,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       1,
+				Threads:     6,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 3, y: 6},
-				{x: 5, y: 6},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
-				{x: 4, y: 8},
+				{X: 3, Y: 6},
+				{X: 5, Y: 6},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
+				{X: 4, Y: 8},
 			},
 		}},
 
 		{"16x16x6-100", args{
 			p: golParams{
-				turns:       100,
-				threads:     6,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       100,
+				Threads:     6,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 12, y: 0},
-				{x: 13, y: 0},
-				{x: 14, y: 0},
-				{x: 13, y: 14},
-				{x: 14, y: 15},
+				{X: 12, Y: 0},
+				{X: 13, Y: 0},
+				{X: 14, Y: 0},
+				{X: 13, Y: 14},
+				{X: 14, Y: 15},
 			},
 		}},
 
 		{"16x16x10-0", args{
 			p: golParams{
-				turns:       0,
-				threads:     10,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       0,
+				Threads:     10,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 4, y: 5},
-				{x: 5, y: 6},
-				{x: 3, y: 7},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
+				{X: 4, Y: 5},
+				{X: 5, Y: 6},
+				{X: 3, Y: 7},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
 			},
 		}},
 
 		{"16x16x10-1", args{
 			p: golParams{
-				turns:       1,
-				threads:     10,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       1,
+				Threads:     10,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 3, y: 6},
-				{x: 5, y: 6},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
-				{x: 4, y: 8},
+				{X: 3, Y: 6},
+				{X: 5, Y: 6},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
+				{X: 4, Y: 8},
 			},
 		}},
 
 		{"16x16x10-100", args{
 			p: golParams{
-				turns:       100,
-				threads:     10,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       100,
+				Threads:     10,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 12, y: 0},
-				{x: 13, y: 0},
-				{x: 14, y: 0},
-				{x: 13, y: 14},
-				{x: 14, y: 15},
+				{X: 12, Y: 0},
+				{X: 13, Y: 0},
+				{X: 14, Y: 0},
+				{X: 13, Y: 14},
+				{X: 14, Y: 15},
 			},
 		}},
 
 		{"16x16x12-0", args{
 			p: golParams{
-				turns:       0,
-				threads:     12,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       0,
+				Threads:     12,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 4, y: 5},
-				{x: 5, y: 6},
-				{x: 3, y: 7},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
+				{X: 4, Y: 5},
+				{X: 5, Y: 6},
+				{X: 3, Y: 7},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
 			},
 		}},
 
 		{"16x16x12-1", args{
 			p: golParams{
-				turns:       1,
-				threads:     12,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       1,
+				Threads:     12,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 3, y: 6},
-				{x: 5, y: 6},
-				{x: 4, y: 7},
-				{x: 5, y: 7},
-				{x: 4, y: 8},
+				{X: 3, Y: 6},
+				{X: 5, Y: 6},
+				{X: 4, Y: 7},
+				{X: 5, Y: 7},
+				{X: 4, Y: 8},
 			},
 		}},
 
 		{"16x16x12-100", args{
 			p: golParams{
-				turns:       100,
-				threads:     12,
-				imageWidth:  16,
-				imageHeight: 16,
+				Turns:       100,
+				Threads:     12,
+				ImageWidth:  16,
+				ImageHeight: 16,
 			},
 			expectedAlive: []cell{
-				{x: 12, y: 0},
-				{x: 13, y: 0},
-				{x: 14, y: 0},
-				{x: 13, y: 14},
-				{x: 14, y: 15},
+				{X: 12, Y: 0},
+				{X: 13, Y: 0},
+				{X: 14, Y: 0},
+				{X: 13, Y: 14},
+				{X: 14, Y: 15},
 			},
 		}},
 
 		// Special test to be used to generate traces - not a real test
 		//{"trace", args{
 		//	p: golParams{
-		//		turns:       10,
-		//		threads:     4,
-		//		imageWidth:  64,
-		//		imageHeight: 64,
+		//		Turns:       10,
+		//		Threads:     4,
+		//		ImageWidth:  64,
+		//		ImageHeight: 64,
 		//	},
 		//}},
 	}
@@ -321,6 +344,1745 @@ func Test(t *testing.T) {
 				assert.ElementsMatch(t, alive, test.args.expectedAlive)
 			}
 		})
+		t.Run(test.name+"-bits", func(t *testing.T) {
+			p := test.args.p
+			p.Backend = gol.BitBackend
+			alive := gameOfLife(p, nil)
+			if test.name != "trace" {
+				assert.ElementsMatch(t, alive, test.args.expectedAlive)
+			}
+		})
+		for _, backend := range []gol.Backend{gol.ByteBackend, gol.BitBackend} {
+			t.Run(test.name+"-shared-"+backend.String(), func(t *testing.T) {
+				p := test.args.p
+				p.Shared, p.Backend = true, backend
+				alive := gameOfLife(p, nil)
+				if test.name != "trace" {
+					assert.ElementsMatch(t, alive, test.args.expectedAlive)
+				}
+			})
+		}
+	}
+}
+
+// worldOf builds a world of the given size with the given cells alive.
+func worldOf(width, height int, alive []cell) *gol.World {
+	world := gol.NewWorld(width, height)
+	for _, c := range alive {
+		world.Set(c.X, c.Y, gol.Alive)
+	}
+	return world
+}
+
+func TestRules(t *testing.T) {
+	replicator := []cell{
+		{X: 7, Y: 5}, {X: 8, Y: 5}, {X: 9, Y: 5},
+		{X: 6, Y: 6}, {X: 9, Y: 6},
+		{X: 5, Y: 7}, {X: 9, Y: 7},
+		{X: 5, Y: 8}, {X: 8, Y: 8},
+		{X: 5, Y: 9}, {X: 6, Y: 9}, {X: 7, Y: 9},
+	}
+	square := []cell{
+		{X: 6, Y: 6}, {X: 7, Y: 6}, {X: 8, Y: 6},
+		{X: 6, Y: 7}, {X: 7, Y: 7}, {X: 8, Y: 7},
+		{X: 6, Y: 8}, {X: 7, Y: 8}, {X: 8, Y: 8},
+	}
+	rPentomino := []cell{
+		{X: 7, Y: 6}, {X: 8, Y: 6},
+		{X: 6, Y: 7}, {X: 7, Y: 7},
+		{X: 7, Y: 8},
+	}
+	tests := []struct {
+		name          string
+		rule          string
+		turns         int
+		start         []cell
+		expectedAlive []cell
+	}{
+		{"life-square", "B3/S23", 1, square, []cell{
+			{X: 7, Y: 5},
+			{X: 6, Y: 6}, {X: 8, Y: 6},
+			{X: 5, Y: 7}, {X: 9, Y: 7},
+			{X: 6, Y: 8}, {X: 8, Y: 8},
+			{X: 7, Y: 9},
+		}},
+		// A replicator makes two copies of itself after 12 turns
+		{"highlife-replicator", "B36/S23", 12, replicator, []cell{
+			{X: 5, Y: 3}, {X: 6, Y: 3}, {X: 7, Y: 3},
+			{X: 4, Y: 4}, {X: 7, Y: 4},
+			{X: 3, Y: 5}, {X: 7, Y: 5},
+			{X: 3, Y: 6}, {X: 6, Y: 6},
+			{X: 3, Y: 7}, {X: 4, Y: 7}, {X: 5, Y: 7}, {X: 9, Y: 7}, {X: 10, Y: 7}, {X: 11, Y: 7},
+			{X: 8, Y: 8}, {X: 11, Y: 8},
+			{X: 7, Y: 9}, {X: 11, Y: 9},
+			{X: 7, Y: 10}, {X: 10, Y: 10},
+			{X: 7, Y: 11}, {X: 8, Y: 11}, {X: 9, Y: 11},
+		}},
+		{"seeds-domino", "B2/S", 1, []cell{{X: 1, Y: 1}, {X: 2, Y: 1}}, []cell{
+			{X: 1, Y: 0}, {X: 2, Y: 0},
+			{X: 1, Y: 2}, {X: 2, Y: 2},
+		}},
+		{"daynight-square", "B3678/S34678", 1, square, []cell{
+			{X: 7, Y: 5},
+			{X: 6, Y: 6}, {X: 8, Y: 6},
+			{X: 5, Y: 7}, {X: 7, Y: 7}, {X: 9, Y: 7},
+			{X: 6, Y: 8}, {X: 8, Y: 8},
+			{X: 7, Y: 9},
+		}},
+		{"maze-r-pentomino", "B3/S12345", 3, rPentomino, []cell{
+			{X: 6, Y: 5}, {X: 7, Y: 5}, {X: 8, Y: 5},
+			{X: 5, Y: 6}, {X: 6, Y: 6}, {X: 7, Y: 6}, {X: 8, Y: 6},
+			{X: 5, Y: 7}, {X: 6, Y: 7}, {X: 8, Y: 7},
+			{X: 5, Y: 8}, {X: 6, Y: 8}, {X: 7, Y: 8},
+		}},
+		// The same as Maze but written in the older S/B notation
+		{"maze-sb-notation", "12345/3", 3, rPentomino, []cell{
+			{X: 6, Y: 5}, {X: 7, Y: 5}, {X: 8, Y: 5},
+			{X: 5, Y: 6}, {X: 6, Y: 6}, {X: 7, Y: 6}, {X: 8, Y: 6},
+			{X: 5, Y: 7}, {X: 6, Y: 7}, {X: 8, Y: 7},
+			{X: 5, Y: 8}, {X: 6, Y: 8}, {X: 7, Y: 8},
+		}},
+	}
+	for _, test := range tests {
+		for _, threads := range []int{1, 4} {
+			t.Run(test.name+"x"+strconv.Itoa(threads), func(t *testing.T) {
+				p := golParams{
+					Turns:   test.turns,
+					Threads: threads,
+					Rule:    gol.MustParseRule(test.rule),
+				}
+				alive := gol.Run(p, worldOf(16, 16, test.start))
+				assert.ElementsMatch(t, test.expectedAlive, alive)
+			})
+		}
+	}
+}
+
+func TestGenerations(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     gol.Rule
+		turns    int
+		start    map[cell]int
+		expected map[cell]int
+	}{
+		// A Brian's Brain spaceship moves up one row every turn, leaving a trail of dying cells
+		{"briansbrain-spaceship", gol.BriansBrain, 4,
+			map[cell]int{{X: 6, Y: 6}: 1, {X: 7, Y: 6}: 1, {X: 6, Y: 7}: 2, {X: 7, Y: 7}: 2},
+			map[cell]int{{X: 6, Y: 2}: 1, {X: 7, Y: 2}: 1, {X: 6, Y: 3}: 2, {X: 7, Y: 3}: 2},
+		},
+		{"starwars-t-tetromino", gol.StarWars, 2,
+			map[cell]int{{X: 6, Y: 6}: 1, {X: 7, Y: 6}: 1, {X: 8, Y: 6}: 1, {X: 7, Y: 7}: 1},
+			map[cell]int{
+				{X: 7, Y: 4}: 1,
+				{X: 6, Y: 5}: 2, {X: 8, Y: 5}: 2,
+				{X: 6, Y: 6}: 3, {X: 7, Y: 6}: 1, {X: 8, Y: 6}: 3,
+				{X: 6, Y: 7}: 1, {X: 7, Y: 7}: 2, {X: 8, Y: 7}: 1,
+			},
+		},
+	}
+	for _, test := range tests {
+		for _, threads := range []int{1, 4} {
+			t.Run(test.name+"x"+strconv.Itoa(threads), func(t *testing.T) {
+				start := gol.NewWorld(16, 16)
+				for c, state := range test.start {
+					start.Set(c.X, c.Y, test.rule.Level(state))
+				}
+				p := golParams{Turns: test.turns, Threads: threads, Rule: test.rule}
+				final := gol.Start(p, start).Result()
+
+				expected := gol.NewWorld(16, 16)
+				for c, state := range test.expected {
+					expected.Set(c.X, c.Y, test.rule.Level(state))
+				}
+				assert.Equal(t, expected, final)
+			})
+		}
+	}
+}
+
+// The glider in images/16x16.pgm wraps around the torus but crashes into the corner when the edges are dead
+func TestBoundaries(t *testing.T) {
+	tests := []struct {
+		boundary      gol.Boundary
+		expectedAlive []cell
+	}{
+		{gol.Torus, []cell{
+			{X: 12, Y: 0}, {X: 13, Y: 0}, {X: 14, Y: 0},
+			{X: 13, Y: 14},
+			{X: 14, Y: 15},
+		}},
+		{gol.DeadEdges, []cell{
+			{X: 12, Y: 14}, {X: 13, Y: 14},
+			{X: 12, Y: 15}, {X: 13, Y: 15},
+		}},
+		{gol.KleinBottle, []cell{
+			{X: 12, Y: 0}, {X: 13, Y: 0}, {X: 14, Y: 0},
+			{X: 2, Y: 14},
+			{X: 1, Y: 15},
+		}},
+		{gol.CrossSurface, []cell{}},
+		{gol.Mirror, []cell{
+			{X: 12, Y: 7}, {X: 15, Y: 7},
+			{X: 13, Y: 8}, {X: 14, Y: 8},
+			{X: 5, Y: 9},
+			{X: 5, Y: 10}, {X: 15, Y: 10},
+			{X: 15, Y: 11},
+			{X: 4, Y: 12}, {X: 5, Y: 12}, {X: 6, Y: 12}, {X: 7, Y: 12}, {X: 8, Y: 12}, {X: 15, Y: 12},
+			{X: 4, Y: 13}, {X: 5, Y: 13}, {X: 6, Y: 13}, {X: 15, Y: 13},
+			{X: 5, Y: 14}, {X: 6, Y: 14},
+			{X: 15, Y: 15},
+		}},
+	}
+	for _, test := range tests {
+		for _, threads := range []int{2, 6} {
+			t.Run(test.boundary.String()+"-16x16x"+strconv.Itoa(threads)+"-100", func(t *testing.T) {
+				p := golParams{
+					Turns:       100,
+					Threads:     threads,
+					ImageWidth:  16,
+					ImageHeight: 16,
+					Boundary:    test.boundary,
+				}
+				alive := gameOfLife(p, nil)
+				assert.ElementsMatch(t, test.expectedAlive, alive)
+			})
+		}
+	}
+}
+
+// A glider heading for the top left corner, where the twist of the Klein bottle and cross-surface matter
+func TestBoundaryCorner(t *testing.T) {
+	glider := []cell{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1}, {X: 1, Y: 2}, {X: 2, Y: 3}}
+	tests := []struct {
+		boundary      gol.Boundary
+		expectedAlive []cell
+	}{
+		{gol.Torus, []cell{{X: 10, Y: 9}, {X: 11, Y: 9}, {X: 10, Y: 10}, {X: 12, Y: 10}, {X: 10, Y: 11}}},
+		{gol.DeadEdges, []cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}}},
+		{gol.KleinBottle, []cell{{X: 4, Y: 9}, {X: 5, Y: 9}, {X: 3, Y: 10}, {X: 5, Y: 10}, {X: 5, Y: 11}}},
+		{gol.CrossSurface, []cell{{X: 0, Y: 0}, {X: 15, Y: 15}}},
+		{gol.Mirror, []cell{{X: 2, Y: 1}, {X: 3, Y: 1}, {X: 2, Y: 2}, {X: 3, Y: 2}}},
+	}
+	for _, test := range tests {
+		for _, threads := range []int{1, 3, 4} {
+			t.Run(test.boundary.String()+"x"+strconv.Itoa(threads), func(t *testing.T) {
+				p := golParams{Turns: 30, Threads: threads, Boundary: test.boundary}
+				alive := gol.Run(p, worldOf(16, 16, glider))
+				assert.ElementsMatch(t, test.expectedAlive, alive)
+			})
+		}
+		t.Run(test.boundary.String()+"-step", func(t *testing.T) {
+			p := golParams{Boundary: test.boundary}
+			world := worldOf(16, 16, glider)
+			for turn := 0; turn < 30; turn++ {
+				world = gol.Step(p, world)
+			}
+			assert.ElementsMatch(t, test.expectedAlive, world.Alive())
+		})
+	}
+}
+
+// randomWorld fills roughly a third of a world with live cells, always the same way for the same seed.
+func randomWorld(width, height int, seed int64) *gol.World {
+	random := rand.New(rand.NewSource(seed))
+	world := gol.NewWorld(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if random.Intn(3) == 0 {
+				world.Set(x, y, gol.Alive)
+			}
+		}
+	}
+	return world
+}
+
+// The bits backend should agree with the byte backend, including on worlds that don't fill a whole number of words
+func TestBitBackend(t *testing.T) {
+	sizes := []struct{ width, height int }{{16, 16}, {63, 20}, {64, 9}, {65, 17}, {130, 31}, {1, 5}}
+	rules := []gol.Rule{gol.Conway, gol.HighLife, gol.Seeds, gol.DayNight, gol.MustParseRule("B0/S8")}
+	boundaries := []gol.Boundary{gol.Torus, gol.DeadEdges, gol.KleinBottle, gol.CrossSurface, gol.Mirror}
+	for i, size := range sizes {
+		for _, rule := range rules {
+			for _, boundary := range boundaries {
+				name := strconv.Itoa(size.width) + "x" + strconv.Itoa(size.height) + "-" + rule.String() + "-" + boundary.String()
+				t.Run(name, func(t *testing.T) {
+					p := golParams{Turns: 20, Threads: 3, Rule: rule, Boundary: boundary}
+					expected := gol.Run(p, randomWorld(size.width, size.height, int64(i)))
+					p.Backend = gol.BitBackend
+					alive := gol.Run(p, randomWorld(size.width, size.height, int64(i)))
+					assert.ElementsMatch(t, expected, alive)
+
+					world := randomWorld(size.width, size.height, int64(i))
+					assert.Equal(t, gol.Step(golParams{Rule: rule, Boundary: boundary}, world), gol.Step(p, world))
+				})
+			}
+		}
+	}
+	assert.Error(t, golParams{Threads: 1, ImageWidth: 16, ImageHeight: 16, Rule: gol.BriansBrain, Backend: gol.BitBackend}.Validate())
+}
+
+// HashLife works on an infinite plane, so the glider in images/16x16.pgm carries on past the edge of the world
+func TestHashLife(t *testing.T) {
+	glider := []cell{{X: 4, Y: 5}, {X: 5, Y: 6}, {X: 3, Y: 7}, {X: 4, Y: 7}, {X: 5, Y: 7}}
+	moved := func(distance int) []cell {
+		var cells []cell
+		for _, c := range glider {
+			cells = append(cells, cell{X: c.X + distance, Y: c.Y + distance})
+		}
+		return cells
+	}
+
+	t.Run("16x16-100", func(t *testing.T) {
+		p := golParams{Turns: 100, ImageWidth: 16, ImageHeight: 16, Engine: gol.HashLife}
+		assert.ElementsMatch(t, moved(25), gameOfLife(p, nil))
+	})
+
+	t.Run("16x16-2^40", func(t *testing.T) {
+		p := golParams{Turns: 1 << 40, Engine: gol.HashLife}
+		game := gol.Start(p, worldOf(16, 16, glider))
+		assert.ElementsMatch(t, moved(1<<38), game.Wait())
+		assert.Empty(t, game.Result().Alive())
+	})
+
+	//Far enough from the dead edges that they make no difference, the workers should agree with HashLife
+	for seed := int64(0); seed < 3; seed++ {
+		soup := gol.NewWorld(160, 160)
+		for _, c := range randomWorld(16, 16, seed).Alive() {
+			soup.Set(c.X+72, c.Y+72, gol.Alive)
+		}
+		t.Run("soup-"+strconv.FormatInt(seed, 10), func(t *testing.T) {
+			p := golParams{Turns: 64, Threads: 4, Boundary: gol.DeadEdges}
+			expected := gol.Run(p, soup)
+			p.Engine = gol.HashLife
+			assert.ElementsMatch(t, expected, gol.Run(p, soup))
+			//Collecting garbage all the time should only slow it down
+			p.MaxNodes = 16
+			assert.ElementsMatch(t, expected, gol.Run(p, soup))
+		})
+	}
+
+	assert.Error(t, golParams{ImageWidth: 16, ImageHeight: 16, Rule: gol.BriansBrain, Engine: gol.HashLife}.Validate())
+	assert.Error(t, golParams{ImageWidth: 16, ImageHeight: 16, Rule: gol.MustParseRule("B03/S23"), Engine: gol.HashLife}.Validate())
+	assert.NoError(t, golParams{ImageWidth: 16, ImageHeight: 16, Engine: gol.HashLife}.Validate())
+}
+
+// The sparse engine also works on an infinite plane, so it should agree with HashLife wherever the cells go
+func TestSparse(t *testing.T) {
+	glider := []cell{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1}, {X: 1, Y: 2}, {X: 2, Y: 3}}
+	t.Run("16x16-100", func(t *testing.T) {
+		p := golParams{Turns: 100, ImageWidth: 16, ImageHeight: 16, Engine: gol.Sparse}
+		expected := []cell{{X: 29, Y: 30}, {X: 30, Y: 31}, {X: 28, Y: 32}, {X: 29, Y: 32}, {X: 30, Y: 32}}
+		assert.ElementsMatch(t, expected, gameOfLife(p, nil))
+	})
+
+	//Heading up and to the left, the glider crosses into tiles with negative coordinates
+	t.Run("glider-400", func(t *testing.T) {
+		var expected []cell
+		for _, c := range glider {
+			expected = append(expected, cell{X: c.X - 100, Y: c.Y - 100})
+		}
+		game := gol.Start(golParams{Turns: 400, Engine: gol.Sparse}, worldOf(16, 16, glider))
+		assert.ElementsMatch(t, expected, game.Wait())
+		assert.Empty(t, game.Result().Alive())
+	})
+
+	for seed := int64(0); seed < 3; seed++ {
+		t.Run("soup-"+strconv.FormatInt(seed, 10), func(t *testing.T) {
+			p := golParams{Turns: 300, Engine: gol.HashLife}
+			expected := gol.Run(p, randomWorld(100, 70, seed))
+			p.Engine = gol.Sparse
+			assert.ElementsMatch(t, expected, gol.Run(p, randomWorld(100, 70, seed)))
+		})
+	}
+	assert.Error(t, golParams{ImageWidth: 16, ImageHeight: 16, Rule: gol.StarWars, Engine: gol.Sparse}.Validate())
+}
+
+func TestRegion(t *testing.T) {
+	glider := []cell{{X: -4, Y: 7}, {X: -3, Y: 8}, {X: -5, Y: 9}, {X: -4, Y: 9}, {X: -3, Y: 9}}
+	x, y, width, height := gol.Bounds(glider)
+	assert.Equal(t, []int{-5, 7, 3, 3}, []int{x, y, width, height})
+
+	world, origin := gol.Region(glider)
+	assert.Equal(t, cell{X: -5, Y: 7}, origin)
+	assert.ElementsMatch(t, []cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}, world.Alive())
+
+	var rle strings.Builder
+	assert.NoError(t, writeRle(&rle, world, gol.Conway, nil))
+	assert.Equal(t, "x = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n", rle.String())
+
+	_, _, width, height = gol.Bounds(nil)
+	assert.Equal(t, []int{0, 0}, []int{width, height})
+}
+
+func TestRle(t *testing.T) {
+	file, err := os.Open("patterns/gosper-glider-gun.rle")
+	assert.NoError(t, err)
+	defer file.Close()
+	gun, err := readRle(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{36, 9}, []int{gun.width, gun.height})
+	assert.Equal(t, "B3/S23", gun.rule)
+	assert.Equal(t, "#N Gosper glider gun", gun.comments[0])
+	assert.Len(t, gun.cells, 36)
+
+	//Writing the gun out and reading it back in should give the same pattern
+	world := gol.NewWorld(gun.width, gun.height)
+	gun.place(world, cell{})
+	var rle strings.Builder
+	assert.NoError(t, writeRle(&rle, world, gol.Conway, gun.comments))
+	for _, line := range strings.Split(rle.String(), "\n") {
+		assert.True(t, len(line) <= 70, line)
+	}
+	again, err := readRle(strings.NewReader(rle.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, gun, again)
+
+	blank, err := readRle(strings.NewReader("x=4,y=5\n2$3bo\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []cell{{X: 3, Y: 2}}, blank.cells)
+
+	for _, bad := range []string{"", "bo$2bo$3o!", "x = 2, y = 2\n3o!", "x = 3, y = 3\nbo$2bo$3?!", "x = three, y = 3\n!"} {
+		_, err := readRle(strings.NewReader(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+// runFiles runs gameOfLifeFiles, failing the test if the input can't be read.
+func runFiles(t *testing.T, p golParams, files fileParams) []cell {
+	alive, err := gameOfLifeFiles(p, files, nil)
+	assert.NoError(t, err)
+	return alive
+}
+
+// The glider in images/16x16.pgm, read from an RLE file instead
+func TestRleInput(t *testing.T) {
+	p := golParams{Turns: 100, Threads: 4, ImageWidth: 16, ImageHeight: 16}
+	expected := gameOfLife(p, nil)
+	files := fileParams{input: "patterns/glider.rle", offset: cell{X: 3, Y: 5}}
+	assert.ElementsMatch(t, expected, runFiles(t, p, files))
+
+	//The gun should fire the same gliders whichever engine runs it
+	p = golParams{Turns: 120, Threads: 4, ImageWidth: 200, ImageHeight: 200, Boundary: gol.DeadEdges}
+	files = fileParams{input: "patterns/gosper-glider-gun.rle", offset: cell{X: 50, Y: 50}}
+	expected = runFiles(t, p, files)
+	assert.Len(t, expected, 36+4*5)
+	p.Engine = gol.Sparse
+	assert.ElementsMatch(t, expected, runFiles(t, p, files))
+}
+
+// The glider in patterns/ is saved in every format, which should all read the same
+func TestPatternFormats(t *testing.T) {
+	glider := []cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	for _, path := range []string{"patterns/glider.rle", "patterns/glider.cells", "patterns/glider.lif"} {
+		pat, err := readPattern(path)
+		assert.NoError(t, err, path)
+		assert.ElementsMatch(t, glider, pat.cells, path)
+		assert.Equal(t, []int{3, 3}, []int{pat.width, pat.height}, path)
+	}
+	_, err := readPattern("patterns/glider.txt")
+	assert.Error(t, err)
+
+	world := worldOf(5, 4, glider)
+	for _, format := range []string{".rle", ".cells", ".lif"} {
+		var out strings.Builder
+		assert.NoError(t, writePattern(&out, format, world, cell{}, gol.Conway, []string{"#N Glider"}))
+		var pat *pattern
+		switch format {
+		case ".rle":
+			pat, err = readRle(strings.NewReader(out.String()))
+		case ".cells":
+			pat, err = readCells(strings.NewReader(out.String()))
+			assert.Equal(t, []string{"#C Glider"}, pat.comments)
+		case ".lif":
+			pat, err = readLife106(strings.NewReader(out.String()))
+		}
+		assert.NoError(t, err, format)
+		assert.ElementsMatch(t, glider, pat.cells, format)
+	}
+
+	//Life 1.06 keeps the position of each cell on the plane, other formats only the shape
+	var out strings.Builder
+	assert.NoError(t, writePattern(&out, ".lif", world, cell{X: -10, Y: 3}, gol.Conway, nil))
+	assert.Equal(t, "#Life 1.06\n-9 3\n-8 4\n-10 5\n-9 5\n-8 5\n", out.String())
+	out.Reset()
+	assert.NoError(t, writePattern(&out, ".rle", world, cell{X: -10, Y: 3}, gol.Conway, nil))
+	assert.Equal(t, "#P -10 3\nx = 5, y = 4, rule = B3/S23\nbo$2bo$3o!\n", out.String())
+
+	for _, bad := range []string{"#Life 1.05\n0 0\n", "#Life 1.06\n0\n", "#Life 1.06\nx 1\n"} {
+		_, err := readLife106(strings.NewReader(bad))
+		assert.Error(t, err, bad)
+	}
+	_, err = readCells(strings.NewReader(".O.\n..x\n"))
+	assert.Error(t, err)
+
+	p := golParams{Turns: 100, Threads: 4, ImageWidth: 16, ImageHeight: 16}
+	expected := gameOfLife(p, nil)
+	for _, path := range []string{"patterns/glider.cells", "patterns/glider.lif"} {
+		files := fileParams{input: path, offset: cell{X: 3, Y: 5}, format: ".cells"}
+		assert.ElementsMatch(t, expected, runFiles(t, p, files), path)
+	}
+}
+
+// Worlds read from -input take their size from the file, unless they are padded or cropped
+func TestInput(t *testing.T) {
+	p := golParams{Turns: 100, Threads: 4, ImageWidth: 16, ImageHeight: 16}
+	expected := gameOfLife(p, nil)
+	p.ImageWidth, p.ImageHeight = 0, 0
+	assert.ElementsMatch(t, expected, runFiles(t, p, fileParams{input: "images/16x16.pgm"}))
+
+	//The glider is 3x3, so with nothing to pad it out it only fits with the offset it needs
+	world, err := readInput("patterns/glider.rle", 0, gol.Conway)
+	assert.NoError(t, err)
+	fitted := fitWorld(world, 0, 0, cell{X: 3, Y: 5})
+	assert.Equal(t, []int{6, 8}, []int{fitted.Width(), fitted.Height()})
+	assert.ElementsMatch(t, []cell{{X: 4, Y: 5}, {X: 5, Y: 6}, {X: 3, Y: 7}, {X: 4, Y: 7}, {X: 5, Y: 7}}, fitted.Alive())
+
+	//Padding the 16x16 image out to 32x32 gives the glider more room before it wraps around
+	p = golParams{Turns: 20, Threads: 4, ImageWidth: 32, ImageHeight: 32}
+	padded := worldOf(32, 32, []cell{{X: 4, Y: 5}, {X: 5, Y: 6}, {X: 3, Y: 7}, {X: 4, Y: 7}, {X: 5, Y: 7}})
+	assert.ElementsMatch(t, gol.Run(p, padded), runFiles(t, p, fileParams{input: "images/16x16.pgm"}))
+
+	//Cropping cuts off the cells that don't fit
+	cropped := fitWorld(world, 2, 2, cell{X: -1, Y: 0})
+	assert.ElementsMatch(t, []cell{{X: 0, Y: 0}, {X: 1, Y: 1}}, cropped.Alive())
+
+	bad, err := ioutil.TempFile("", "bad*.pgm")
+	assert.NoError(t, err)
+	defer os.Remove(bad.Name())
+	_, _ = bad.WriteString("P5\n16 16\n255\nshort")
+	bad.Close()
+	for _, path := range []string{"images/missing.pgm", bad.Name(), "patterns/glider.txt"} {
+		_, err := gameOfLifeFiles(golParams{Turns: 1, Threads: 1}, fileParams{input: path}, nil)
+		assert.Error(t, err, path)
+	}
+
+}
+
+// The glider from patterns/glider.rle drawn in every kind of netpbm image
+func TestNetpbm(t *testing.T) {
+	glider := []cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	images := map[string]string{
+		"P1":        "P1\n# a glider\n3 3\n010\n001\n111\n",
+		"P1-spaced": "P1 3 3 0 1 0 0 0 1 1 1 1",
+		"P2":        "P2\n3 3\n# maxval below\n15\n0 15 0\n0 0 15\n15 15 15\n",
+		"P4":        "P4\n3 3\n\x40\x20\xe0",
+		"P5":        "P5\n#comment\n3 3 255\n\x00\xff\x00\x00\x00\xff\xff\xff\xff",
+		"P5-16bit":  "P5 3 3 65535\n\x00\x00\xff\xff\x00\x00\x00\x00\x00\x00\xff\xff\xff\xff\xff\xff\xff\xff",
+	}
+	for name, image := range images {
+		world, err := readNetpbm(strings.NewReader(image), 0, gol.Conway)
+		assert.NoError(t, err, name)
+		assert.ElementsMatch(t, glider, world.Alive(), name)
+	}
+
+	//Raster bytes that look like whitespace or comments are still pixels, and with 256 states every grey level is one
+	levels := gol.MustParseRule("B3/S23/C256")
+	world, err := readNetpbm(strings.NewReader("P5 4 1 255\n\x20\x0a#\xff"), 0, levels)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{32, 10, '#', 255}, []byte{world.Get(0, 0), world.Get(1, 0), world.Get(2, 0), world.Get(3, 0)})
+
+	//Grey levels are snapped to the nearest state of the rule unless there is a threshold, and are scaled to 8 bits either way
+	grey := "P2 4 1 1000 0 499 500 1000"
+	world, err = readNetpbm(strings.NewReader(grey), 0, levels)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 127, 128, 255}, []byte{world.Get(0, 0), world.Get(1, 0), world.Get(2, 0), world.Get(3, 0)})
+	world, err = readNetpbm(strings.NewReader(grey), 0, gol.Conway)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 255, 255}, []byte{world.Get(0, 0), world.Get(1, 0), world.Get(2, 0), world.Get(3, 0)})
+	world, err = readNetpbm(strings.NewReader(grey), 0, gol.BriansBrain)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 128, 128, 255}, []byte{world.Get(0, 0), world.Get(1, 0), world.Get(2, 0), world.Get(3, 0)})
+	world, err = readNetpbm(strings.NewReader(grey), 128, gol.Conway)
+	assert.NoError(t, err)
+	assert.Equal(t, []cell{{X: 2, Y: 0}, {X: 3, Y: 0}}, world.Alive())
+
+	for _, bad := range []string{"", "P3 1 1 255 0 0 0", "P5 0 1 255\n\xff", "P5 1 1 65536\n\xff\xff", "P5 1 x 255\n\xff",
+		"P5 2 2 255\n\xff", "P2 2 1 7 3 8", "P1 2 1 0 2", "P2 2 1 7 3"} {
+		_, err := readNetpbm(strings.NewReader(bad), 0, gol.Conway)
+		assert.Error(t, err, bad)
+	}
+}
+
+// Grey levels that aren't states of the rule should be read in the same way whichever backend runs the game
+func TestNetpbmBackends(t *testing.T) {
+	//A blinker with a grey middle, which needs to be read as alive to keep blinking
+	world, err := readNetpbm(strings.NewReader("P2 6 6 255\n0 0 0 0 0 0\n0 0 0 0 0 0\n0 255 128 255 0 0\n"+
+		"0 0 0 0 0 0\n0 0 0 0 0 0\n0 0 0 0 0 0\n"), 0, gol.Conway)
+	assert.NoError(t, err)
+	blinker := []cell{{X: 2, Y: 1}, {X: 2, Y: 2}, {X: 2, Y: 3}}
+	backends := []golParams{
+		{Threads: 2},
+		{Threads: 2, Backend: gol.BitBackend},
+		{Threads: 2, Shared: true},
+		{Threads: 2, Backend: gol.BitBackend, Shared: true},
+	}
+	for _, p := range backends {
+		p.Turns, p.ImageWidth, p.ImageHeight = 3, 6, 6
+		game := gol.Start(p, world)
+		assert.ElementsMatch(t, blinker, game.Wait(), p.Backend.String())
+		result := game.Result()
+		for y := 0; y < 6; y++ {
+			for x := 0; x < 6; x++ {
+				assert.Contains(t, []byte{0, gol.Alive}, result.Get(x, y), p.Backend.String())
+			}
+		}
+	}
+}
+
+// Snapshots should put every worker's rows back in the right place, and survive being written as a pgm
+func TestSnapshot(t *testing.T) {
+	//Blocks are still lifes, so every snapshot should be the same as the world the game started with
+	var blocks []cell
+	for y := 1; y < 30; y += 4 {
+		for x := 1; x < 30; x += 7 {
+			blocks = append(blocks, cell{X: x, Y: y}, cell{X: x + 1, Y: y}, cell{X: x, Y: y + 1}, cell{X: x + 1, Y: y + 1})
+		}
+	}
+	engines := []golParams{
+		{Turns: 1000, Threads: 7},
+		{Turns: 1000, Threads: 3, Backend: gol.BitBackend},
+		{Turns: 1000, Engine: gol.Sparse},
+	}
+	for _, p := range engines {
+		t.Run(p.Engine.String()+"-"+p.Backend.String(), func(t *testing.T) {
+			game := gol.Start(p, worldOf(32, 32, blocks))
+			assert.ElementsMatch(t, blocks, game.Snapshot())
+			var pgm bytes.Buffer
+			assert.NoError(t, encodePgm(&pgm, game.SnapshotWorld()))
+			world, err := readNetpbm(&pgm, 0, p.Rule)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, blocks, world.Alive())
+			game.Wait()
+		})
+	}
+}
+
+// Resuming from a checkpoint should end in the same state as a game that was never stopped
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := dir + "/checkpoint.gz"
+
+	glider := []cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	games := []struct {
+		p     golParams
+		world *gol.World
+	}{
+		{golParams{Turns: 300, Threads: 5}, randomWorld(40, 40, 3)},
+		{golParams{Turns: 301, Threads: 3, Backend: gol.BitBackend, Boundary: gol.CrossSurface}, randomWorld(70, 20, 4)},
+		{golParams{Turns: 200, Threads: 4, Rule: gol.MustParseRule("B2/S/C3"), Boundary: gol.KleinBottle}, randomWorld(30, 30, 5)},
+		//Gliders that leave the world should come back from the checkpoint
+		{golParams{Turns: 200, Engine: gol.Sparse}, worldOf(16, 16, glider)},
+		{golParams{Turns: 200, Engine: gol.HashLife}, worldOf(16, 16, glider)},
+	}
+	for _, game := range games {
+		p := game.p
+		p.ImageWidth, p.ImageHeight = game.world.Width(), game.world.Height()
+		t.Run(p.Engine.String()+"-"+p.Backend.String()+"-"+p.Boundary.String(), func(t *testing.T) {
+			expected := gol.Run(p, game.world)
+
+			//A checkpoint taken while the game runs
+			running := gol.Start(p, game.world)
+			assert.NoError(t, writeCheckpoint(path, p, soupParams{}, running.State()))
+			running.Wait()
+			assert.ElementsMatch(t, expected, runFiles(t, golParams{}, fileParams{resume: path}))
+
+			//A checkpoint from a turn that is known, part way through
+			p.Turns = 123
+			partway := gol.Start(p, game.world)
+			state := gol.State{Turn: 123, World: partway.Result()}
+			if p.Engine.Unbounded() {
+				for _, c := range partway.Wait() {
+					if c.X < 0 || c.X >= p.ImageWidth || c.Y < 0 || c.Y >= p.ImageHeight {
+						state.Outside = append(state.Outside, c)
+					}
+				}
+			}
+			p.Turns = game.p.Turns
+			assert.NoError(t, writeCheckpoint(path, p, soupParams{}, state))
+			resumed, resumedState, _, err := readCheckpoint(path)
+			assert.NoError(t, err)
+			//The zero rule comes back as the Conway rule it stands for
+			assert.Equal(t, p.Rule.String(), resumed.Rule.String())
+			resumed.Rule = p.Rule
+			assert.Equal(t, p, resumed)
+			assert.Equal(t, 123, resumedState.Turn)
+			assert.ElementsMatch(t, expected, runFiles(t, golParams{}, fileParams{resume: path}))
+		})
+	}
+
+	//Frames of a resumed game carry on from the turn it was resumed at
+	p := golParams{Turns: 20, Threads: 2, FrameEvery: 4}
+	for _, engine := range []gol.Engine{gol.Workers, gol.HashLife} {
+		p.Engine = engine
+		var turns []int
+		game := gol.Resume(p, gol.State{Turn: 7, World: worldOf(16, 16, glider)})
+		for frame := range game.Frames() {
+			turns = append(turns, frame.Turn)
+		}
+		assert.Equal(t, []int{8, 12, 16, 20}, turns, engine.String())
+	}
+
+	for _, bad := range []string{"patterns/glider.rle", dir + "/missing.gz"} {
+		_, _, _, err := readCheckpoint(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// Soups should be the same every time for the same params, and keep to their symmetry and rectangle
+func TestSoup(t *testing.T) {
+	soup := soupParams{on: true, density: 0.3, seed: 42}
+	first, err := generateSoup(256, 256, soup)
+	assert.NoError(t, err)
+	second, _ := generateSoup(256, 256, soup)
+	assert.Equal(t, first.Alive(), second.Alive())
+	density := float64(len(first.Alive())) / (256 * 256)
+	assert.InDelta(t, 0.3, density, 0.02)
+	soup.seed = 43
+	other, _ := generateSoup(256, 256, soup)
+	assert.NotEqual(t, first.Alive(), other.Alive())
+
+	//Every image of a live cell under the symmetry should be alive, and nothing outside the rectangle
+	rect := rectangle{x: 5, y: 7, width: 20, height: 20}
+	for _, sym := range []symmetry{c1, c2, c4, d8} {
+		world, err := generateSoup(40, 30, soupParams{on: true, density: 0.4, seed: 1, symmetry: sym, rect: rect})
+		assert.NoError(t, err, sym.String())
+		for _, c := range world.Alive() {
+			assert.True(t, c.X >= rect.x && c.X < rect.x+rect.width && c.Y >= rect.y && c.Y < rect.y+rect.height, sym.String())
+			for _, image := range sym.images(c.X-rect.x, c.Y-rect.y, rect.width, rect.height) {
+				assert.Equal(t, gol.Alive, world.Get(rect.x+image.X, rect.y+image.Y), "%v image of %v", sym, c)
+			}
+		}
+	}
+	full, _ := generateSoup(8, 8, soupParams{on: true, density: 1, rect: rectangle{x: 2, y: 2, width: 3, height: 4}})
+	assert.Len(t, full.Alive(), 12)
+	empty, _ := generateSoup(8, 8, soupParams{on: true, density: 0})
+	assert.Empty(t, empty.Alive())
+
+	for _, bad := range []soupParams{
+		{density: 1.5},
+		{density: 0.5, symmetry: c4},
+		{density: 0.5, symmetry: d8, rect: rectangle{width: 3, height: 4}},
+		{density: 0.5, rect: rectangle{x: 10, y: 0, width: 8, height: 8}},
+	} {
+		_, err := generateSoup(16, 12, bad)
+		assert.Error(t, err, "%+v", bad)
+	}
+
+	//A soup game can be run again from its params, and its checkpoints remember the soup
+	p := golParams{Turns: 50, Threads: 4, ImageWidth: 64, ImageHeight: 64}
+	files := fileParams{soup: soupParams{on: true, density: 0.35, seed: 7, symmetry: c2}}
+	assert.Equal(t, runFiles(t, p, files), runFiles(t, p, files))
+	dir, err := ioutil.TempDir("", "soup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	world, _ := generateSoup(64, 64, files.soup)
+	assert.NoError(t, writeCheckpoint(dir+"/soup.gz", p, files.soup, gol.State{World: world}))
+	_, _, resumed, err := readCheckpoint(dir + "/soup.gz")
+	assert.NoError(t, err)
+	assert.Equal(t, files.soup, resumed)
+}
+
+// Cycles should be found however the world is split between workers,
+// and stopping early should end in the same world as running every turn
+func TestCycles(t *testing.T) {
+	blinker := []cell{{X: 4, Y: 5}, {X: 5, Y: 5}, {X: 6, Y: 5}}
+	block := []cell{{X: 4, Y: 4}, {X: 5, Y: 4}, {X: 4, Y: 5}, {X: 5, Y: 5}}
+	glider := []cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	tests := []struct {
+		name  string
+		world *gol.World
+		cycle gol.Cycle
+	}{
+		{"blinker", worldOf(16, 16, blinker), gol.Cycle{Turn: 2, Period: 2, Population: 3}},
+		{"block", worldOf(16, 16, block), gol.Cycle{Turn: 1, Period: 1, Population: 4}},
+		{"dies", worldOf(16, 16, []cell{{X: 3, Y: 3}}), gol.Cycle{Turn: 2, Period: 1}},
+		{"glider", worldOf(16, 16, glider), gol.Cycle{Turn: 4, Period: 4, DX: 1, DY: 1, Population: 5}},
+	}
+	for _, test := range tests {
+		for _, p := range []golParams{
+			{Turns: 1003, Threads: 1},
+			{Turns: 1002, Threads: 3},
+			{Turns: 1001, Threads: 5, Backend: gol.BitBackend},
+		} {
+			t.Run(test.name+"x"+strconv.Itoa(p.Threads), func(t *testing.T) {
+				expected := gol.Run(p, test.world)
+				p.StopOnCycle = true
+				game := gol.Start(p, test.world)
+				assert.ElementsMatch(t, expected, game.Wait())
+				assert.Equal(t, test.cycle, game.Cycle())
+			})
+		}
+	}
+
+	//Spaceships are only reported when the world has edges they could run into
+	p := golParams{Turns: 100, Threads: 4, Boundary: gol.DeadEdges}
+	expected := gol.Run(p, worldOf(16, 16, glider))
+	p.StopOnCycle = true
+	game := gol.Start(p, worldOf(16, 16, glider))
+	assert.ElementsMatch(t, expected, game.Wait())
+	assert.Equal(t, "period 4 spaceship moving 1,1 from turn 4", game.Cycle().String())
+
+	//Soups settle down into all sorts of oscillators
+	for seed := int64(1); seed <= 5; seed++ {
+		p := golParams{Turns: 3000, Threads: 4, Boundary: gol.KleinBottle}
+		world, _ := generateSoup(32, 32, soupParams{density: 0.4, seed: seed})
+		expected := gol.Run(p, world)
+		p.StopOnCycle = true
+		assert.ElementsMatch(t, expected, gol.Run(p, world), "seed %d", seed)
+	}
+
+	//Without StopOnCycle every turn is run, and a game that never repeats finds nothing
+	rPentomino := []cell{{X: 11, Y: 10}, {X: 12, Y: 10}, {X: 10, Y: 11}, {X: 11, Y: 11}, {X: 11, Y: 12}}
+	game = gol.Start(golParams{Turns: 10, Threads: 2, DetectCycles: true}, worldOf(32, 32, rPentomino))
+	game.Wait()
+	assert.False(t, game.Cycle().Found())
+
+	//Repeats are checked before they are reported, which takes a period after the world first repeats
+	blinkerCycle := gol.Cycle{Turn: 2, Period: 2, Population: 3}
+	game = gol.Start(golParams{Turns: 6, Threads: 2, DetectCycles: true}, worldOf(16, 16, blinker))
+	game.Wait()
+	assert.Equal(t, blinkerCycle, game.Cycle())
+	game = gol.Start(golParams{Turns: 5, Threads: 2, DetectCycles: true}, worldOf(16, 16, blinker))
+	game.Wait()
+	assert.False(t, game.Cycle().Found())
+
+	//A billion turns of a blinker should take no time at all
+	game = gol.Start(golParams{Turns: 1000000001, Threads: 2, StopOnCycle: true}, worldOf(16, 16, blinker))
+	assert.ElementsMatch(t, []cell{{X: 5, Y: 4}, {X: 5, Y: 5}, {X: 5, Y: 6}}, game.Wait())
+
+	assert.Error(t, golParams{ImageWidth: 16, ImageHeight: 16, DetectCycles: true, Engine: gol.HashLife}.Validate())
+	assert.Error(t, golParams{ImageWidth: 16, ImageHeight: 16, Threads: 1, StopOnCycle: true, FrameEvery: 2}.Validate())
+}
+
+// Stats should match the world every StatsEvery turns, with each worker's population adding up to the whole
+func TestStats(t *testing.T) {
+	world, _ := generateSoup(40, 37, soupParams{density: 0.4, seed: 7})
+	//The expected stats of each turn are worked out from the alive cells of it and the turn before
+	alive := func(turn int) map[cell]bool {
+		cells := make(map[cell]bool)
+		for _, c := range gol.Run(golParams{Turns: turn, Threads: 1}, world) {
+			cells[c] = true
+		}
+		return cells
+	}
+	expected := func(turn int) gol.Stats {
+		now := alive(turn)
+		s := gol.Stats{Turn: turn, Population: len(now)}
+		var cells []cell
+		for c := range now {
+			cells = append(cells, c)
+		}
+		s.X, s.Y, s.Width, s.Height = gol.Bounds(cells)
+		if turn > 0 {
+			before := alive(turn - 1)
+			for c := range now {
+				if !before[c] {
+					s.Births++
+				}
+			}
+			for c := range before {
+				if !now[c] {
+					s.Deaths++
+				}
+			}
+		}
+		return s
+	}
+
+	for _, p := range []golParams{
+		{Turns: 30, Threads: 1, StatsEvery: 1},
+		{Turns: 31, Threads: 3, StatsEvery: 3},
+		{Turns: 30, Threads: 5, StatsEvery: 5, Backend: gol.BitBackend},
+		{Turns: 30, Threads: 8, StatsEvery: 2, Backend: gol.BitBackend},
+	} {
+		t.Run(p.Backend.String()+"x"+strconv.Itoa(p.Threads), func(t *testing.T) {
+			game := gol.Start(p, world)
+			turn := 0
+			for s := range game.Stats() {
+				assert.Len(t, s.Workers, p.Threads)
+				total := 0
+				for _, population := range s.Workers {
+					total += population
+				}
+				assert.Equal(t, s.Population, total)
+				s.Workers = nil
+				assert.Equal(t, expected(turn), s)
+				turn += p.StatsEvery
+			}
+			assert.Equal(t, p.Turns/p.StatsEvery*p.StatsEvery+p.StatsEvery, turn, "every turn should be recorded")
+			game.Wait()
+		})
+	}
+
+	//A resumed game starts recording at the first multiple of StatsEvery it reaches
+	p := golParams{Turns: 20, Threads: 2, StatsEvery: 4}
+	game := gol.Resume(p, gol.State{Turn: 7, World: gol.NewWorld(16, 16)})
+	var turns []int
+	for s := range game.Stats() {
+		turns = append(turns, s.Turn)
+	}
+	assert.Equal(t, []int{8, 12, 16, 20}, turns)
+
+	//A game that stops early on a cycle records its last turn, which is a period after the repeat was found
+	blinker := []cell{{X: 4, Y: 5}, {X: 5, Y: 5}, {X: 6, Y: 5}}
+	game = gol.Start(golParams{Turns: 1000001, Threads: 2, StopOnCycle: true, StatsEvery: 1}, worldOf(16, 16, blinker))
+	var last gol.Stats
+	for s := range game.Stats() {
+		last = s
+	}
+	game.Wait()
+	assert.Equal(t, gol.Stats{Turn: 5, Population: 3, Births: 2, Deaths: 2, X: 5, Y: 4, Width: 1, Height: 3, Workers: []int{3, 0}}, last)
+
+	assert.Error(t, golParams{ImageWidth: 16, ImageHeight: 16, Threads: 1, StatsEvery: -1}.Validate())
+	assert.Error(t, golParams{ImageWidth: 16, ImageHeight: 16, StatsEvery: 1, Engine: gol.Sparse}.Validate())
+
+	//Stats are written out as csv or json lines
+	dir, err := ioutil.TempDir("", "stats")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	record := func(path string) string {
+		stats := make(chan gol.Stats, 2)
+		stats <- gol.Stats{Turn: 0, Population: 3, X: 1, Y: 2, Width: 3, Height: 1, Workers: []int{3, 0}}
+		stats <- gol.Stats{Turn: 1, Population: 3, Births: 2, Deaths: 2, X: 2, Y: 1, Width: 1, Height: 3, Workers: []int{2, 1}}
+		close(stats)
+		assert.NoError(t, recordStats(stats, path))
+		contents, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		return string(contents)
+	}
+	assert.Equal(t, "turn,population,births,deaths,x,y,width,height,worker_0,worker_1\n0,3,0,0,1,2,3,1,3,0\n1,3,2,2,2,1,1,3,2,1\n",
+		record(dir+"/stats.csv"))
+	assert.Equal(t, `{"turn":0,"population":3,"births":0,"deaths":0,"x":1,"y":2,"width":3,"height":1,"workers":[3,0]}`+"\n"+
+		`{"turn":1,"population":3,"births":2,"deaths":2,"x":2,"y":1,"width":1,"height":3,"workers":[2,1]}`+"\n",
+		record(dir+"/stats.jsonl"))
+	assert.Error(t, checkStatsPath("stats.txt"))
+}
+
+func TestControl(t *testing.T) {
+	world, _ := generateSoup(64, 64, soupParams{density: 0.3, seed: 3})
+	for _, p := range []golParams{
+		{Turns: 100000000, Threads: 3},
+		{Turns: 100000000, Threads: 1, Engine: gol.Sparse},
+	} {
+		t.Run(p.Engine.String(), func(t *testing.T) {
+			game := gol.Start(p, world)
+			quits := 0
+			server := httptest.NewServer(controlHandler(p, fileParams{}, game, func() { quits++ }))
+			defer server.Close()
+			request := func(method, path string) (*http.Response, []byte) {
+				req, err := http.NewRequest(method, server.URL+path, nil)
+				assert.NoError(t, err)
+				response, err := http.DefaultClient.Do(req)
+				assert.NoError(t, err)
+				defer response.Body.Close()
+				body, err := ioutil.ReadAll(response.Body)
+				assert.NoError(t, err)
+				return response, body
+			}
+			status := func(method, path string) controlStatus {
+				response, body := request(method, path)
+				assert.Equal(t, http.StatusOK, response.StatusCode, string(body))
+				var s controlStatus
+				assert.NoError(t, json.Unmarshal(body, &s))
+				return s
+			}
+
+			running := status("GET", "/status")
+			assert.False(t, running.Paused)
+			assert.Equal(t, p.Turns, running.Turns)
+			response, _ := request("GET", "/pause")
+			assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+
+			//While paused the world stays as it was at the turn the game was paused at
+			paused := status("POST", "/pause")
+			assert.True(t, paused.Paused)
+			assert.Equal(t, paused, status("GET", "/status"))
+			at := p
+			at.Turns = paused.Turn
+			expected := gol.Run(at, world)
+			assert.Len(t, expected, paused.Alive)
+
+			_, body := request("GET", "/world.pgm")
+			pgm, err := readNetpbm(bytes.NewReader(body), 0, p.Rule)
+			assert.NoError(t, err)
+			if !p.Engine.Unbounded() {
+				assert.ElementsMatch(t, expected, pgm.Alive())
+			}
+			assert.Len(t, pgm.Alive(), paused.Alive)
+			_, body = request("GET", "/world.rle")
+			assert.True(t, strings.HasPrefix(string(body), "#C Turn "+strconv.Itoa(paused.Turn)+"\n"), string(body))
+			pat, err := readRle(bytes.NewReader(body))
+			assert.NoError(t, err)
+			assert.Len(t, pat.cells, paused.Alive)
+			response, _ = request("GET", "/world.png")
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+			resumed := status("POST", "/resume")
+			assert.False(t, resumed.Paused)
+			assert.True(t, status("GET", "/status").Turn >= paused.Turn)
+			status("POST", "/quit")
+			assert.Equal(t, 1, quits)
+
+			//The game is left paused so that it doesn't carry on after the test
+			game.Pause()
+		})
+	}
+
+	//Once a game has finished, every count is of the final world rather than one left behind by the workers
+	game := gol.Start(golParams{Turns: 11, Threads: 4}, worldOf(16, 16, []cell{{X: 4, Y: 5}, {X: 5, Y: 5}, {X: 6, Y: 5}}))
+	game.Wait()
+	for i := 0; i < 100; i++ {
+		turn, alive := game.Count()
+		assert.Equal(t, []int{11, 3}, []int{turn, alive})
+	}
+}
+
+// Quitting should stop the game where it is, writing out the world and a checkpoint and finishing the gif and stats
+func TestQuit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	p := golParams{Turns: 1000000000, Threads: 2, ImageWidth: 16, ImageHeight: 16}
+	files := fileParams{gif: dir + "/quit.gif", gifEvery: 1, stats: dir + "/quit.csv", statsEvery: 1, checkpoint: dir + "/quit.gz"}
+	keys := make(chan rune, 1)
+	keys <- 'q'
+	alive, err := gameOfLifeFiles(p, files, keys)
+	assert.NoError(t, err)
+
+	_, state, _, err := readCheckpoint(files.checkpoint)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, state.World.Alive(), alive)
+	file, err := os.Open(files.gif)
+	assert.NoError(t, err)
+	defer file.Close()
+	animation, err := gif.DecodeAll(file)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, animation.Image)
+	stats, err := ioutil.ReadFile(files.stats)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(stats), "turn,population"))
+
+	//The control API only listens on localhost unless it is told otherwise
+	assert.Equal(t, "localhost:8080", controlAddress(":8080"))
+	assert.Equal(t, "0.0.0.0:8080", controlAddress("0.0.0.0:8080"))
+}
+
+func TestPauseKey(t *testing.T) {
+	p := golParams{Turns: 100000000, Threads: 2}
+	game := gol.Start(p, randomWorld(64, 64, 8))
+	keys := make(chan rune)
+	quits := 0
+	done := make(chan bool)
+	go func() {
+		keyboardInputs(p, fileParams{}, keys, game, nil, func() { quits++ })
+		done <- true
+	}()
+
+	//A game paused over HTTP is resumed by the next p rather than left waiting for another
+	paused, ok := game.Pause()
+	assert.True(t, ok)
+	keys <- 'p'
+	for game.Paused() {
+		time.Sleep(time.Millisecond)
+	}
+	for {
+		if turn, _ := game.Count(); turn > paused {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	keys <- 'p'
+	for !game.Paused() {
+		time.Sleep(time.Millisecond)
+	}
+
+	//Keys are still read while the game is paused
+	keys <- 'q'
+	<-done
+	assert.Equal(t, 1, quits)
+	assert.True(t, game.Paused())
+}
+
+func TestController(t *testing.T) {
+	assert.Equal(t, "http://localhost:8080", newController(":8080").url)
+	assert.Equal(t, "http://example.com:8080", newController("example.com:8080").url)
+	assert.Equal(t, "https://example.com", newController("https://example.com/").url)
+
+	p := golParams{Turns: 100000000, Threads: 2}
+	game := gol.Start(p, randomWorld(64, 64, 8))
+	quits := 0
+	server := httptest.NewServer(controlHandler(p, fileParams{}, game, func() { quits++ }))
+	defer server.Close()
+
+	c := newController(server.URL)
+	message, stop, err := c.key('p')
+	assert.NoError(t, err)
+	assert.False(t, stop)
+	assert.True(t, strings.HasSuffix(message, "Paused"), message)
+	paused, err := c.status()
+	assert.NoError(t, err)
+	assert.True(t, paused.Paused)
+	message, _, err = c.key('s')
+	assert.NoError(t, err)
+	assert.Equal(t, "Snapshot of turn "+strconv.Itoa(paused.Turn)+" written by the server", message)
+	message, _, err = c.key('p')
+	assert.NoError(t, err)
+	assert.Equal(t, "Continuing", message)
+	message, stop, err = c.key('x')
+	assert.NoError(t, err)
+	assert.Equal(t, "", message)
+	assert.False(t, stop)
+
+	//Disconnecting leaves the game running for the next controller to pick up
+	_, stop, err = c.key('q')
+	assert.NoError(t, err)
+	assert.True(t, stop)
+	assert.Equal(t, 0, quits)
+	for {
+		if turn, _ := game.Count(); turn > paused.Turn {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	again := newController(server.URL)
+	running, err := again.status()
+	assert.NoError(t, err)
+	assert.False(t, running.Paused)
+	assert.True(t, running.Turn > paused.Turn)
+	_, stop, err = again.key('k')
+	assert.NoError(t, err)
+	assert.True(t, stop)
+	assert.Equal(t, 1, quits)
+
+	game.Pause()
+	server.Close()
+	_, err = again.status()
+	assert.Error(t, err)
+}
+
+func TestViewer(t *testing.T) {
+	//The example handshake from RFC 6455
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="))
+
+	world, _ := generateSoup(64, 48, soupParams{density: 0.3, seed: 5})
+	p := golParams{Turns: 3000, Threads: 4}
+	game := gol.Start(p, world)
+	server := httptest.NewServer(controlHandler(p, fileParams{}, game, func() {}))
+	defer server.Close()
+
+	connect := func(query string) (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		assert.NoError(t, err)
+		request := "GET /live" + query + " HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+		_, err = conn.Write([]byte(request))
+		assert.NoError(t, err)
+		reader := bufio.NewReader(conn)
+		response, err := http.ReadResponse(reader, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+		assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", response.Header.Get("Sec-WebSocket-Accept"))
+		return conn, reader
+	}
+
+	//Every diff is applied to the world the viewer has, which ends up as the final world
+	conn, reader := connect("?fps=60")
+	defer conn.Close()
+	var cells []byte
+	messages := 0
+	turn := -1
+	for {
+		opcode, message, err := readFrame(reader, 1<<30)
+		assert.NoError(t, err)
+		if opcode == opClose {
+			break
+		}
+		assert.Equal(t, opBinary, opcode)
+		header := make([]int, 6)
+		for i := range header {
+			header[i] = int(binary.LittleEndian.Uint32(message[1+4*i:]))
+		}
+		assert.Equal(t, []int{64, 48}, header[1:3])
+		assert.True(t, header[0] > turn, "turns should only go forwards")
+		turn = header[0]
+		if messages == 0 {
+			assert.Equal(t, viewerWorld, message[0])
+			cells = make([]byte, 64*48)
+		} else {
+			assert.Equal(t, viewerDiff, message[0])
+		}
+		for i := 0; i < header[4]+header[5]; i++ {
+			index := binary.LittleEndian.Uint32(message[viewerHeader+4*i:])
+			born := i < header[4]
+			assert.Equal(t, !born, cells[index] == gol.Alive, "cell %d should have changed", index)
+			cells[index] = 0
+			if born {
+				cells[index] = gol.Alive
+			}
+		}
+		assert.Equal(t, header[3], bytes.Count(cells, []byte{gol.Alive}))
+		messages++
+	}
+	assert.Equal(t, p.Turns, turn)
+	var alive []cell
+	for i, state := range cells {
+		if state == gol.Alive {
+			alive = append(alive, cell{X: i % 64, Y: i / 64})
+		}
+	}
+	assert.ElementsMatch(t, game.Wait(), alive)
+
+	//The stream stops when the viewer closes it
+	paused := gol.Start(golParams{Turns: 100000000, Threads: 2}, world)
+	pausedServer := httptest.NewServer(controlHandler(p, fileParams{}, paused, func() {}))
+	defer pausedServer.Close()
+	paused.Pause()
+	server = pausedServer
+	conn, reader = connect("")
+	defer conn.Close()
+	opcode, _, err := readFrame(reader, 1<<30)
+	assert.NoError(t, err)
+	assert.Equal(t, opBinary, opcode)
+	_, err = conn.Write([]byte{0x88, 0x80, 1, 2, 3, 4})
+	assert.NoError(t, err)
+	opcode, _, err = readFrame(reader, 1<<30)
+	assert.NoError(t, err)
+	assert.Equal(t, opClose, opcode)
+
+	//Viewers asking at the same time share a snapshot rather than each stopping the workers for one
+	feed := newViewerFeed(paused)
+	first, _ := feed.current()
+	second, _ := feed.current()
+	assert.True(t, first.World == second.World)
+
+	for _, path := range []string{"/live", "/live?fps=0", "/live?fps=61"} {
+		response, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		response.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode, path)
+	}
+	response, err := http.Get(server.URL + "/")
+	assert.NoError(t, err)
+	page, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Contains(t, string(page), "new WebSocket(")
+	response, err = http.Get(server.URL + "/missing")
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestTerminalView(t *testing.T) {
+	//A glider in the top left and a single cell in the bottom right corner of a 10x8 world
+	world := worldOf(10, 8, []cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}, {X: 9, Y: 7}})
+	lines := func(v *terminalView, columns, rows int) []string {
+		var out []string
+		for _, line := range v.render(world, columns, rows) {
+			out = append(out, string(line))
+		}
+		return out
+	}
+
+	half := &terminalView{mode: viewHalf, zoom: 1}
+	assert.Equal(t, []string{
+		" ▀▄       ",
+		"▀▀▀       ",
+		"          ",
+		"         ▄",
+		"          ",
+	}, lines(half, 10, 5), "characters past the world should be blank")
+
+	braille := &terminalView{mode: viewBraille, zoom: 1}
+	assert.Equal(t, []string{"\u282c\u2806   ", "    \u2880"}, lines(braille, 5, 2))
+
+	//Zoomed out, each dot stands for a square of cells
+	zoomed := &terminalView{mode: viewHalf, zoom: 2}
+	assert.Equal(t, []string{"██   ", "    ▄"}, lines(zoomed, 5, 2))
+
+	//Panning stops at the edges of the world, and worlds that fit stay in the top left
+	v := &terminalView{mode: viewHalf, zoom: 1}
+	assert.True(t, v.key(rune(termbox.KeyArrowRight), 4, 2))
+	assert.True(t, v.key(rune(termbox.KeyArrowDown), 4, 2))
+	assert.Equal(t, 1, v.x)
+	assert.Equal(t, 1, v.y)
+	for i := 0; i < 10; i++ {
+		v.key(rune(termbox.KeyArrowRight), 4, 2)
+		v.clamp(10, 8, 4, 2)
+	}
+	assert.Equal(t, 6, v.x)
+	assert.Equal(t, []string{"   ▄"}, lines(&terminalView{mode: viewHalf, zoom: 1, x: 6, y: 6}, 4, 1))
+	v.clamp(10, 8, 20, 20)
+	assert.Equal(t, 0, v.x)
+	assert.Equal(t, 0, v.y)
+
+	//Zooming keeps the middle of the screen where it is
+	v = &terminalView{mode: viewHalf, zoom: 2, x: 100, y: 100}
+	assert.True(t, v.key('+', 10, 10))
+	assert.Equal(t, terminalView{mode: viewHalf, zoom: 1, x: 105, y: 110}, *v)
+	assert.True(t, v.key('-', 10, 10))
+	assert.Equal(t, terminalView{mode: viewHalf, zoom: 2, x: 100, y: 100}, *v)
+	assert.True(t, v.key('v', 10, 10))
+	assert.Equal(t, viewBraille, v.mode)
+	assert.False(t, v.key('x', 10, 10))
+
+	assert.True(t, strings.HasPrefix(v.status(12, 34, 56.4, true), "Turn 12  Alive 34  56 turns/s"))
+	assert.Contains(t, v.status(12, 34, 56.4, true), "Paused")
+	_, err := parseViewMode("sixel")
+	assert.Error(t, err)
+}
+
+//Starts n worker processes' worth of cluster workers on localhost, returning their addresses
+//and a function that stops them listening
+func startWorkers(t testing.TB, n int) ([]string, func()) {
+	var addresses []string
+	var listeners []net.Listener
+	for i := 0; i < n; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		go cluster.Serve(listener)
+		listeners = append(listeners, listener)
+		addresses = append(addresses, listener.Addr().String())
+	}
+	return addresses, func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}
+}
+
+func TestCluster(t *testing.T) {
+	defer func(batch int) { cluster.BatchTurns = batch }(cluster.BatchTurns)
+	cluster.BatchTurns = 7
+	addresses, stop := startWorkers(t, 5)
+	defer stop()
+
+	world, _ := generateSoup(40, 23, soupParams{density: 0.4, seed: 11})
+	for _, p := range []golParams{
+		{Turns: 50, Boundary: gol.Torus},
+		{Turns: 50, Boundary: gol.DeadEdges, Backend: gol.BitBackend},
+		{Turns: 50, Boundary: gol.KleinBottle},
+		{Turns: 50, Boundary: gol.Mirror, Backend: gol.BitBackend},
+		{Turns: 30, Rule: gol.MustParseRule("B2/S/C3")},
+	} {
+		for _, workers := range []int{1, 2, 5} {
+			t.Run(p.Boundary.String()+"-"+p.Rule.String()+"x"+strconv.Itoa(workers), func(t *testing.T) {
+				local := p
+				local.Threads = 1
+				expected := gol.Start(local, world).Result()
+
+				game, err := cluster.Start(p, gol.State{World: world}, addresses[:workers])
+				assert.NoError(t, err)
+				final, err := game.Wait()
+				assert.NoError(t, err)
+				assert.Equal(t, p.Turns, final.Turn)
+				assert.Equal(t, expected, final.World)
+			})
+		}
+	}
+
+	//A game part way through can be looked at, and carried on from there
+	p := golParams{Turns: 100000}
+	game, err := cluster.Start(p, gol.State{World: world}, addresses[:3])
+	assert.NoError(t, err)
+	state, err := game.State()
+	assert.NoError(t, err)
+	expected := gol.Run(golParams{Turns: state.Turn, Threads: 1}, world)
+	assert.ElementsMatch(t, expected, state.World.Alive())
+	turn, alive := game.Count()
+	assert.True(t, turn >= state.Turn)
+	assert.True(t, alive >= 0)
+
+	resumed, err := cluster.Start(golParams{Turns: state.Turn + 20}, state, addresses[3:])
+	assert.NoError(t, err)
+	final, err := resumed.Wait()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, gol.Run(golParams{Turns: state.Turn + 20, Threads: 1}, world), final.World.Alive())
+
+	_, err = cluster.Start(golParams{Turns: 1, Boundary: gol.CrossSurface}, gol.State{World: world}, addresses[:1])
+	assert.Error(t, err)
+	_, err = cluster.Start(golParams{Turns: 1, StatsEvery: 1}, gol.State{World: world}, addresses[:1])
+	assert.Error(t, err)
+	_, err = cluster.Start(golParams{Turns: 1}, gol.State{World: world}, []string{"127.0.0.1:1"})
+	assert.Error(t, err)
+}
+
+// TestWorkerProcess is run by startWorkerProcess in a process of its own, and is skipped otherwise.
+func TestWorkerProcess(t *testing.T) {
+	address := os.Getenv("GOL_TEST_WORKER")
+	if address == "" {
+		t.Skip("only run as a worker process")
+	}
+	serveWorker(address)
+}
+
+// startWorkerProcess runs a worker in another process, returning its address and a function that kills it.
+func startWorkerProcess(t *testing.T) (string, func()) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestWorkerProcess$")
+	cmd.Env = append(os.Environ(), "GOL_TEST_WORKER=127.0.0.1:0")
+	stdout, err := cmd.StdoutPipe()
+	assert.NoError(t, err)
+	assert.NoError(t, cmd.Start())
+	//serveWorker prints the address it is listening on before anything else
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.NoError(t, err)
+	fields := strings.Fields(line)
+	return fields[len(fields)-1], func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+}
+
+// hangingWorker stops answering pings and running turns once hang is closed, as if its process had frozen.
+type hangingWorker struct {
+	*cluster.Worker
+	hang, stop chan bool
+}
+
+func (w *hangingWorker) wait() {
+	select {
+	case <-w.hang:
+		<-w.stop
+	default:
+	}
+}
+
+func (w *hangingWorker) Ping(args bool, reply *bool) error {
+	w.wait()
+	return w.Worker.Ping(args, reply)
+}
+
+func (w *hangingWorker) Step(args cluster.StepArgs, reply *cluster.StepReply) error {
+	w.wait()
+	return w.Worker.Step(args, reply)
+}
+
+func TestClusterFaults(t *testing.T) {
+	defer func(batch, checkpoint int, every, timeout time.Duration) {
+		cluster.BatchTurns, cluster.CheckpointTurns = batch, checkpoint
+		cluster.HeartbeatEvery, cluster.HeartbeatTimeout = every, timeout
+	}(cluster.BatchTurns, cluster.CheckpointTurns, cluster.HeartbeatEvery, cluster.HeartbeatTimeout)
+	cluster.BatchTurns, cluster.CheckpointTurns = 5, 20
+	cluster.HeartbeatEvery, cluster.HeartbeatTimeout = 20*time.Millisecond, 500*time.Millisecond
+
+	world, _ := generateSoup(48, 31, soupParams{density: 0.4, seed: 5})
+	p := golParams{Turns: 1500, Boundary: gol.KleinBottle}
+	local := p
+	local.Threads = 1
+	expected := gol.Start(local, world).Result()
+
+	//Waits for the game to get past turn, so that whatever happens next happens mid-run
+	reach := func(game *cluster.Game, turn int) {
+		for {
+			if reached, _ := game.Count(); reached >= turn {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	//Killing workers mid-run rolls the game back onto the rest of them, and then a spare that joined
+	t.Run("kill", func(t *testing.T) {
+		var addresses []string
+		var kills []func()
+		for i := 0; i < 4; i++ {
+			address, kill := startWorkerProcess(t)
+			defer kill()
+			addresses = append(addresses, address)
+			kills = append(kills, kill)
+		}
+		game, err := cluster.Start(p, gol.State{World: world}, addresses[:3])
+		assert.NoError(t, err)
+		assert.NoError(t, game.Join(addresses[3]))
+		reach(game, 200)
+		kills[1]()
+		reach(game, 600)
+		kills[0]()
+		final, err := game.Wait()
+		assert.NoError(t, err)
+		assert.Equal(t, p.Turns, final.Turn)
+		assert.Equal(t, expected, final.World)
+		rollbacks, turn := game.Rollbacks()
+		assert.Equal(t, 2, rollbacks)
+		assert.True(t, turn >= 580, "rolled back to turn %d", turn)
+	})
+
+	//A worker that stops answering without its connections closing is found by its heartbeat
+	t.Run("hang", func(t *testing.T) {
+		addresses, stop := startWorkers(t, 2)
+		defer stop()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+		hanging := &hangingWorker{Worker: cluster.NewWorker(), hang: make(chan bool), stop: make(chan bool)}
+		defer close(hanging.stop)
+		server := rpc.NewServer()
+		assert.NoError(t, server.RegisterName("Worker", hanging))
+		go server.Accept(listener)
+
+		game, err := cluster.Start(p, gol.State{World: world}, append(addresses, listener.Addr().String()))
+		assert.NoError(t, err)
+		reach(game, 200)
+		close(hanging.hang)
+		final, err := game.Wait()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, final.World)
+		rollbacks, _ := game.Rollbacks()
+		assert.Equal(t, 1, rollbacks)
+	})
+
+	//The game fails once every worker has
+	t.Run("all", func(t *testing.T) {
+		address, kill := startWorkerProcess(t)
+		defer kill()
+		game, err := cluster.Start(golParams{Turns: 1000000}, gol.State{World: world}, []string{address})
+		assert.NoError(t, err)
+		reach(game, 50)
+		kill()
+		_, err = game.Wait()
+		assert.Error(t, err)
+	})
+}
+
+// TestWorkerLoad checks that Loads arriving together or late can't crash a worker or roll it back.
+func TestWorkerLoad(t *testing.T) {
+	addresses, stop := startWorkers(t, 2)
+	defer stop()
+	world, _ := generateSoup(16, 16, soupParams{density: 0.3, seed: 1})
+	load := func(epoch, turn int) cluster.LoadArgs {
+		args := cluster.LoadArgs{Epoch: epoch, Turn: turn, Width: 16, Height: 16, Rule: "B3/S23", Boundary: "torus",
+			Backend: "byte", Above: addresses[0], Below: addresses[1]}
+		for y := 0; y < 16; y++ {
+			args.Rows = append(args.Rows, world.Row(y))
+		}
+		return args
+	}
+	worker := cluster.NewWorker()
+
+	//A retried Load racing the original should leave the worker loaded rather than closing abort twice
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = worker.Load(load(2, 5), new(bool))
+		}(i)
+	}
+	wg.Wait()
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+
+	//A Load delayed from an earlier epoch is turned away, leaving the band as it was
+	assert.Error(t, worker.Load(load(1, 3), new(bool)))
+	var rows cluster.RowsReply
+	assert.NoError(t, worker.Rows(true, &rows))
+	assert.Equal(t, 5, rows.Turn)
+	assert.Error(t, worker.Step(cluster.StepArgs{Epoch: 1, Turns: 1}, new(cluster.StepReply)))
+}
+
+// TestShared checks that workers sharing the world in memory get the same results as those sending each other rows.
+func TestShared(t *testing.T) {
+	for _, size := range [][2]int{{37, 29}, {130, 20}} {
+		world := randomWorld(size[0], size[1], 4)
+		for _, boundary := range []gol.Boundary{gol.Torus, gol.DeadEdges, gol.KleinBottle, gol.CrossSurface, gol.Mirror} {
+			for _, backend := range []gol.Backend{gol.ByteBackend, gol.BitBackend} {
+				for _, threads := range []int{1, 3, 7} {
+					p := golParams{Turns: 40, Threads: threads, Boundary: boundary, Backend: backend}
+					name := strconv.Itoa(size[0]) + "x" + strconv.Itoa(size[1]) + "-" + boundary.String() + "-" + backend.String() + "x" + strconv.Itoa(threads)
+					t.Run(name, func(t *testing.T) {
+						expected := gol.Start(p, world).Result()
+						shared := p
+						shared.Shared = true
+						assert.Equal(t, expected, gol.Start(shared, world).Result())
+					})
+				}
+			}
+		}
+	}
+
+	world := randomWorld(50, 40, 9)
+	//Generations rules, and games carried on from an odd turn
+	for _, p := range []golParams{
+		{Turns: 30, Threads: 4, Rule: gol.MustParseRule("B2/S/C3")},
+		{Turns: 30, Threads: 5, Boundary: gol.CrossSurface},
+	} {
+		shared := p
+		shared.Shared = true
+		assert.Equal(t, gol.Start(p, world).Result(), gol.Start(shared, world).Result())
+		state := gol.State{Turn: 7, World: world}
+		assert.Equal(t, gol.Resume(p, state).Result(), gol.Resume(shared, state).Result())
+	}
+
+	//Stats and cycles come out the same, which needs every worker to carry on to the same turn
+	p := golParams{Turns: 200, Threads: 3, StatsEvery: 10, StopOnCycle: true}
+	shared := p
+	shared.Shared = true
+	var results [2][]gol.Stats
+	var cycles [2]gol.Cycle
+	for i, params := range []golParams{p, shared} {
+		game := gol.Start(params, world)
+		for stats := range game.Stats() {
+			results[i] = append(results[i], stats)
+		}
+		game.Wait()
+		cycles[i] = game.Cycle()
+	}
+	assert.NotEmpty(t, results[1])
+	assert.Equal(t, results[0], results[1])
+	assert.Equal(t, cycles[0], cycles[1])
+
+	//Pausing stops every worker on the same turn
+	game := gol.Start(golParams{Turns: 100000000, Threads: 6, Shared: true}, world)
+	turn, _ := game.Pause()
+	state := game.State()
+	assert.Equal(t, turn, state.Turn)
+	assert.ElementsMatch(t, gol.Run(golParams{Turns: turn, Threads: 1}, world), state.World.Alive())
+	counted, alive := game.Count()
+	assert.Equal(t, turn, counted)
+	assert.Equal(t, len(state.World.Alive()), alive)
+	game.Resume()
+	assert.True(t, game.State().Turn >= turn)
+	game.Pause()
+
+	assert.Error(t, golParams{Turns: 1, ImageWidth: 16, ImageHeight: 16, Engine: gol.Sparse, Shared: true}.Validate())
+}
+
+// Frames should be the world every FrameEvery turns, whichever engine is running
+func TestFrames(t *testing.T) {
+	glider := []cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	engines := []golParams{
+		{Turns: 24, Threads: 3, FrameEvery: 4},
+		{Turns: 22, Threads: 5, FrameEvery: 3, Backend: gol.BitBackend},
+		{Turns: 24, FrameEvery: 4, Engine: gol.Sparse},
+		{Turns: 22, FrameEvery: 3, Engine: gol.HashLife},
+	}
+	for _, p := range engines {
+		t.Run(p.Engine.String()+"x"+strconv.Itoa(p.FrameEvery), func(t *testing.T) {
+			game := gol.Start(p, worldOf(16, 16, glider))
+			var turns []int
+			for frame := range game.Frames() {
+				turns = append(turns, frame.Turn)
+				expected := gol.Run(golParams{Turns: frame.Turn, Threads: 1, Engine: p.Engine}, worldOf(16, 16, glider))
+				assert.ElementsMatch(t, frame.World.Alive(), cropTo(expected, 16, 16), "turn %d", frame.Turn)
+			}
+			var expected []int
+			for turn := 0; turn <= p.Turns; turn += p.FrameEvery {
+				expected = append(expected, turn)
+			}
+			assert.Equal(t, expected, turns)
+			game.Wait()
+		})
+	}
+
+	//Without FrameEvery the frames channel is closed straight away
+	game := gol.Start(golParams{Turns: 10, Threads: 2}, worldOf(16, 16, glider))
+	_, ok := <-game.Frames()
+	assert.False(t, ok)
+}
+
+// cropTo leaves out the cells outside of a width x height world.
+func cropTo(cells []cell, width, height int) []cell {
+	cropped := []cell{}
+	for _, c := range cells {
+		if c.X >= 0 && c.X < width && c.Y >= 0 && c.Y < height {
+			cropped = append(cropped, c)
+		}
+	}
+	return cropped
+}
+
+func TestImages(t *testing.T) {
+	glider := []cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	var pal palette
+	assert.Equal(t, "000000,ffffff", pal.String())
+	assert.NoError(t, pal.Set("102030,#ff8800"))
+	assert.Equal(t, "102030,ff8800", pal.String())
+	for _, bad := range []string{"", "ffffff", "fff,000", "gggggg,000000"} {
+		assert.Error(t, new(palette).Set(bad), bad)
+	}
+
+	//Each cell is a 2x2 square, with dying cells of Generations rules blended between the two colours
+	world := worldOf(3, 3, glider)
+	world.Set(0, 0, gol.BriansBrain.Level(2))
+	var buffer bytes.Buffer
+	assert.NoError(t, writePng(&buffer, world, 2, pal))
+	img, err := png.Decode(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 6, 6), img.Bounds())
+	colour := func(x, y int) color.RGBA {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 255}
+	}
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0x88, A: 0xff}, colour(3, 1))
+	assert.Equal(t, color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff}, colour(5, 1))
+	assert.Equal(t, color.RGBA{R: 0x88, G: 0x54, B: 0x18, A: 0xff}, colour(1, 1))
+
+	dir, err := ioutil.TempDir("", "gif")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	p := golParams{Turns: 100, Threads: 4, ImageWidth: 16, ImageHeight: 16}
+	files := fileParams{gif: dir + "/16x16.gif", gifEvery: 10, scale: 3}
+	expected := gameOfLife(p, nil)
+	assert.ElementsMatch(t, expected, runFiles(t, p, files))
+	file, err := os.Open(files.gif)
+	assert.NoError(t, err)
+	defer file.Close()
+	animation, err := gif.DecodeAll(file)
+	assert.NoError(t, err)
+	assert.Len(t, animation.Image, 11)
+	assert.Equal(t, image.Rect(0, 0, 48, 48), animation.Image[0].Bounds())
+	//The gif is written a frame at a time, which should decode to the same frames as were drawn
+	assert.Equal(t, 10, animation.Delay[10])
+	assert.Equal(t, drawWorld(worldOf(16, 16, expected), 3, palette{}.colours()).Pix, animation.Image[10].Pix)
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rulestring string
+		expected   string
+	}{
+		{"B3/S23", "B3/S23"},
+		{"b36/s23", "B36/S23"},
+		{"S23/B3", "B3/S23"},
+		{"23/3", "B3/S23"},
+		{"B2/S", "B2/S"},
+	}
+	for _, test := range tests {
+		rule, err := gol.ParseRule(test.rulestring)
+		assert.NoError(t, err, test.rulestring)
+		assert.Equal(t, test.expected, rule.String())
+	}
+	for _, bad := range []string{"", "B3", "B39/S23", "B3/S2x", "B3/S23/S4"} {
+		_, err := gol.ParseRule(bad)
+		assert.Error(t, err, bad)
+	}
+	assert.Equal(t, gol.Conway.String(), gol.Rule{}.String())
+
+	generations, err := gol.ParseRule("345/2/4")
+	assert.NoError(t, err)
+	assert.Equal(t, gol.StarWars, generations)
+	assert.Equal(t, "B2/S/C3", gol.BriansBrain.String())
+	for _, bad := range []string{"B2/S/C1", "B2/S/C257", "B2/S/Cx"} {
+		_, err := gol.ParseRule(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// Every state of a Generations rule should be written out as a different grey level
+func TestRuleLevels(t *testing.T) {
+	assert.Equal(t, []byte{0, 255}, []byte{gol.Conway.Level(0), gol.Conway.Level(1)})
+	assert.Equal(t, []byte{0, 255, 128}, []byte{gol.BriansBrain.Level(0), gol.BriansBrain.Level(1), gol.BriansBrain.Level(2)})
+	for _, states := range []int{2, 3, 4, 17, 100, 256} {
+		rule := gol.MustParseRule("B2/S/C" + strconv.Itoa(states))
+		seen := make(map[byte]bool)
+		for state := 0; state < states; state++ {
+			seen[rule.Level(state)] = true
+		}
+		assert.Len(t, seen, states)
+		assert.Equal(t, gol.Alive, rule.Level(1))
 	}
 }
 
@@ -333,122 +2095,122 @@ func Benchmark(b *testing.B) {
 	}{
 		{
 			"16x16x2", golParams{
-			turns:       benchLength,
-			threads:     2,
-			imageWidth:  16,
-			imageHeight: 16,
+			Turns:       benchLength,
+			Threads:     2,
+			ImageWidth:  16,
+			ImageHeight: 16,
 		}},
 
 		{
 			"16x16x4", golParams{
-			turns:       benchLength,
-			threads:     4,
-			imageWidth:  16,
-			imageHeight: 16,
+			Turns:       benchLength,
+			Threads:     4,
+			ImageWidth:  16,
+			ImageHeight: 16,
 		}},
 
 		{
 			"16x16x8", golParams{
-			turns:       benchLength,
-			threads:     8,
-			imageWidth:  16,
-			imageHeight: 16,
+			Turns:       benchLength,
+			Threads:     8,
+			ImageWidth:  16,
+			ImageHeight: 16,
 		}},
 
 		{
 			"64x64x2", golParams{
-			turns:       benchLength,
-			threads:     2,
-			imageWidth:  64,
-			imageHeight: 64,
+			Turns:       benchLength,
+			Threads:     2,
+			ImageWidth:  64,
+			ImageHeight: 64,
 		}},
 
 		{
 			"64x64x4", golParams{
-			turns:       benchLength,
-			threads:     4,
-			imageWidth:  64,
-			imageHeight: 64,
+			Turns:       benchLength,
+			Threads:     4,
+			ImageWidth:  64,
+			ImageHeight: 64,
 		}},
 
 		{
 			"64x64x8", golParams{
-			turns:       benchLength,
-			threads:     8,
-			imageWidth:  64,
-			imageHeight: 64,
+			Turns:       benchLength,
+			Threads:     8,
+			ImageWidth:  64,
+			ImageHeight: 64,
 		}},
 
 		{
 			"128x128x2", golParams{
-			turns:       benchLength,
-			threads:     2,
-			imageWidth:  128,
-			imageHeight: 128,
+			Turns:       benchLength,
+			Threads:     2,
+			ImageWidth:  128,
+			ImageHeight: 128,
 		}},
 
 		{
 			"128x128x4", golParams{
-			turns:       benchLength,
-			threads:     4,
-			imageWidth:  128,
-			imageHeight: 128,
+			Turns:       benchLength,
+			Threads:     4,
+			ImageWidth:  128,
+			ImageHeight: 128,
 		}},
 
 		{
 			"128x128x8", golParams{
-			turns:       benchLength,
-			threads:     8,
-			imageWidth:  128,
-			imageHeight: 128,
+			Turns:       benchLength,
+			Threads:     8,
+			ImageWidth:  128,
+			ImageHeight: 128,
 		}},
 
 		{
 			"256x256x2", golParams{
-			turns:       benchLength,
-			threads:     2,
-			imageWidth:  256,
-			imageHeight: 256,
+			Turns:       benchLength,
+			Threads:     2,
+			ImageWidth:  256,
+			ImageHeight: 256,
 		}},
 
 		{
 			"256x256x4", golParams{
-			turns:       benchLength,
-			threads:     4,
-			imageWidth:  256,
-			imageHeight: 256,
+			Turns:       benchLength,
+			Threads:     4,
+			ImageWidth:  256,
+			ImageHeight: 256,
 		}},
 
 		{
 			"256x256x8", golParams{
-			turns:       benchLength,
-			threads:     8,
-			imageWidth:  256,
-			imageHeight: 256,
+			Turns:       benchLength,
+			Threads:     8,
+			ImageWidth:  256,
+			ImageHeight: 256,
 		}},
 
 		{
 			"512x512x2", golParams{
-			turns:       benchLength,
-			threads:     2,
-			imageWidth:  512,
-			imageHeight: 512,
+			Turns:       benchLength,
+			Threads:     2,
+			ImageWidth:  512,
+			ImageHeight: 512,
 		}},
 
 		{
 			"512x512x4", golParams{
-			turns:       benchLength,
-			threads:     4,
-			imageWidth:  512,
-			imageHeight: 512,
+			Turns:       benchLength,
+			Threads:     4,
+			ImageWidth:  512,
+			ImageHeight: 512,
 		}},
 
 		{
 			"512x512x8", golParams{
-			turns:       benchLength,
-			threads:     8,
-			imageWidth:  512,
-			imageHeight: 512,
+			Turns:       benchLength,
+			Threads:     8,
+			ImageWidth:  512,
+			ImageHeight: 512,
 		}},
 	}
 	for _, bm := range benchmarks {
@@ -459,5 +2221,145 @@ func Benchmark(b *testing.B) {
 				//fmt.Println("Ran bench:", bm.name)
 			}
 		})
+		b.Run(bm.name+"-bits", func(b *testing.B) {
+			p := bm.p
+			p.Backend = gol.BitBackend
+			for i := 0; i < b.N; i++ {
+				gameOfLife(p, nil)
+			}
+		})
+		b.Run(bm.name+"-shared", func(b *testing.B) {
+			p := bm.p
+			p.Shared = true
+			for i := 0; i < b.N; i++ {
+				gameOfLife(p, nil)
+			}
+		})
+	}
+}
+
+//The sizes of the images in images/, which the throughput benchmarks are run at
+var benchSizes = []int{16, 64, 128, 256, 512}
+
+// BenchmarkSnapshot measures what pressing 's' costs: the workers hand over their rows
+// and the world is written out as a pgm.
+// Each turn answers at most one snapshot, so the game is asked for one as often as it can give one for b.N turns.
+func BenchmarkSnapshot(b *testing.B) {
+	for _, size := range benchSizes {
+		world := randomWorld(size, size, 1)
+		b.Run(strconv.Itoa(size)+"x"+strconv.Itoa(size)+"x8", func(b *testing.B) {
+			b.SetBytes(int64(size * size))
+			game := gol.Start(golParams{Turns: b.N, Threads: 8}, world)
+			for i := 0; i < b.N; i++ {
+				if err := encodePgm(ioutil.Discard, game.SnapshotWorld()); err != nil {
+					b.Fatal(err)
+				}
+			}
+			game.Wait()
+		})
+	}
+}
+
+// BenchmarkPgm measures writing a world out as a pgm on its own.
+func BenchmarkPgm(b *testing.B) {
+	for _, size := range benchSizes {
+		world := randomWorld(size, size, 1)
+		b.Run(strconv.Itoa(size)+"x"+strconv.Itoa(size), func(b *testing.B) {
+			b.SetBytes(int64(size * size))
+			for i := 0; i < b.N; i++ {
+				if err := encodePgm(ioutil.Discard, world); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// haloExchange swaps halo rows between threads workers in a ring for turns turns, with nothing else in each turn.
+// With rows set they are sent as whole rows, the way the workers do now, and otherwise a byte at a time
+// over buffered channels, the way they used to, so that BenchmarkHalo can compare the two.
+func haloExchange(width, threads, turns int, rows bool) {
+	//down[i] carries the bottom row of worker i to the worker below it, and up[i] the top row of that worker back up
+	downRows, upRows := make([]chan []byte, threads), make([]chan []byte, threads)
+	downBytes, upBytes := make([]chan byte, threads), make([]chan byte, threads)
+	for i := 0; i < threads; i++ {
+		downRows[i], upRows[i] = make(chan []byte, 1), make(chan []byte, 1)
+		downBytes[i], upBytes[i] = make(chan byte, width*threads*threads), make(chan byte, width*threads*threads)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(i, above int) {
+			defer wg.Done()
+			top, bottom := make([]byte, width), make([]byte, width)
+			sendTops := [2][]byte{make([]byte, width), make([]byte, width)}
+			sendBots := [2][]byte{make([]byte, width), make([]byte, width)}
+			for turn := 0; turn < turns; turn++ {
+				sendTop, sendBot := sendTops[turn%2], sendBots[turn%2]
+				if rows {
+					upRows[above] <- sendTop
+					downRows[i] <- sendBot
+					copy(top, <-downRows[above])
+					copy(bottom, <-upRows[i])
+					continue
+				}
+				send := func() {
+					for x := 0; x < width; x++ {
+						upBytes[above] <- sendTop[x]
+						downBytes[i] <- sendBot[x]
+					}
+				}
+				receive := func() {
+					for x := 0; x < width; x++ {
+						bottom[x] = <-upBytes[i]
+						top[x] = <-downBytes[above]
+					}
+				}
+				//Odd workers sent before receiving, as did the last so that it didn't wait on worker 0
+				if i%2 != 0 || i == threads-1 {
+					send()
+					receive()
+				} else {
+					receive()
+					send()
+				}
+			}
+		}(i, (i+threads-1)%threads)
+	}
+	wg.Wait()
+}
+
+// BenchmarkHalo measures turns that are mostly spent by the workers swapping the rows on the edges of their strips:
+// the worlds are wide and each worker has only two rows of its own. ns/op is the time for one turn,
+// and the throughput is of the halo rows sent between the workers.
+// The swap benchmarks time the swap on its own, a byte at a time as the workers used to and in whole rows
+// as they do now, while the byte and bits benchmarks run whole games with each backend.
+func BenchmarkHalo(b *testing.B) {
+	for _, width := range []int{512, 4096} {
+		for _, threads := range []int{2, 4, 8} {
+			size := strconv.Itoa(width) + "x" + strconv.Itoa(2*threads) + "x" + strconv.Itoa(threads)
+			for _, rows := range []bool{false, true} {
+				name := size + "-swap-bytes"
+				if rows {
+					name = size + "-swap-rows"
+				}
+				b.Run(name, func(b *testing.B) {
+					b.SetBytes(int64(2 * width * threads))
+					haloExchange(width, threads, b.N, rows)
+				})
+			}
+			world := randomWorld(width, 2*threads, 1)
+			for _, backend := range []gol.Backend{gol.ByteBackend, gol.BitBackend} {
+				//The bits backend packs eight cells into each byte of its rows
+				rowBytes := width
+				if backend == gol.BitBackend {
+					rowBytes = (width + 7) / 8
+				}
+				b.Run(size+"-"+backend.String(), func(b *testing.B) {
+					b.SetBytes(int64(2 * rowBytes * threads))
+					gol.Start(golParams{Turns: b.N, Threads: threads, Backend: backend}, world).Wait()
+				})
+			}
+		}
 	}
 }
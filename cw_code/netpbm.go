@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// netpbmExtensions lists the extensions of the netpbm images that can be read.
+var netpbmExtensions = []string{".pgm", ".pbm", ".pnm"}
+
+// readNetpbm reads a P1 or P4 bitmap, or a P2 or P5 greymap with any maxval up to 65535, into a world.
+// Bitmaps have a live cell wherever a pixel is black.
+// Greymaps are scaled to grey levels from 0 to 255. If threshold is 0 each grey level becomes the state of rule
+// whose level is nearest to it, so that images written with Generations rules can be read back in.
+// Otherwise cells are alive where the grey level is at least threshold and dead everywhere else.
+func readNetpbm(r io.Reader, threshold int, rule gol.Rule) (*gol.World, error) {
+	in := bufio.NewReader(r)
+
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(in, magic); err != nil || magic[0] != 'P' {
+		return nil, errors.New("not a netpbm image")
+	}
+	kind := magic[1]
+	if kind != '1' && kind != '2' && kind != '4' && kind != '5' {
+		return nil, errors.New("unsupported netpbm image P" + string(kind) + ", expected P1, P2, P4 or P5")
+	}
+	bitmap := kind == '1' || kind == '4'
+
+	invalid := errors.New("invalid netpbm header")
+	width, err := readHeaderNumber(in)
+	if err != nil {
+		return nil, invalid
+	}
+	height, err := readHeaderNumber(in)
+	if err != nil {
+		return nil, invalid
+	}
+	if width < 1 || height < 1 {
+		return nil, errors.New("invalid image size " + strconv.Itoa(width) + "x" + strconv.Itoa(height))
+	}
+	maxval := 1
+	if !bitmap {
+		if maxval, err = readHeaderNumber(in); err != nil {
+			return nil, invalid
+		}
+		if maxval < 1 || maxval > 65535 {
+			return nil, errors.New("maxval " + strconv.Itoa(maxval) + " should be between 1 and 65535")
+		}
+	}
+	if kind == '4' || kind == '5' {
+		//A single whitespace character separates the header from the raster
+		if _, err := in.ReadByte(); err != nil {
+			return nil, errors.New("image has no raster")
+		}
+	}
+
+	//level turns a sample into the state of a cell
+	level := func(sample int) byte {
+		if bitmap {
+			//In bitmaps 1 is black, which is where the live cells are drawn
+			if sample == 1 {
+				return gol.Alive
+			}
+			return 0
+		}
+		grey := byte((sample*255 + maxval/2) / maxval)
+		if threshold == 0 {
+			return nearestLevel(rule, grey)
+		}
+		if int(grey) >= threshold {
+			return gol.Alive
+		}
+		return 0
+	}
+
+	world := gol.NewWorld(width, height)
+	short := errors.New("image is shorter than its " + strconv.Itoa(width) + "x" + strconv.Itoa(height) + " header")
+	switch kind {
+	case '1':
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				//Plain bitmaps don't need whitespace between pixels
+				b, err := skipSpace(in)
+				if err != nil {
+					return nil, short
+				}
+				if b != '0' && b != '1' {
+					return nil, errors.New("unexpected " + strconv.QuoteRune(rune(b)) + " in P1 image")
+				}
+				world.Set(x, y, level(int(b-'0')))
+			}
+		}
+	case '2':
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				sample, err := readHeaderNumber(in)
+				if err == io.EOF {
+					return nil, short
+				} else if err != nil {
+					return nil, err
+				}
+				if sample > maxval {
+					return nil, errors.New("sample " + strconv.Itoa(sample) + " is bigger than maxval")
+				}
+				world.Set(x, y, level(sample))
+			}
+		}
+	case '4':
+		row := make([]byte, (width+7)/8)
+		for y := 0; y < height; y++ {
+			if _, err := io.ReadFull(in, row); err != nil {
+				return nil, short
+			}
+			for x := 0; x < width; x++ {
+				world.Set(x, y, level(int(row[x/8]>>(7-uint(x%8))&1)))
+			}
+		}
+	case '5':
+		bytesPerSample := 1
+		if maxval > 255 {
+			bytesPerSample = 2
+		}
+		row := make([]byte, width*bytesPerSample)
+		for y := 0; y < height; y++ {
+			if _, err := io.ReadFull(in, row); err != nil {
+				return nil, short
+			}
+			for x := 0; x < width; x++ {
+				sample := int(row[x])
+				if bytesPerSample == 2 {
+					//Samples of 16-bit images are big-endian
+					sample = int(row[2*x])<<8 | int(row[2*x+1])
+				}
+				if sample > maxval {
+					return nil, errors.New("sample " + strconv.Itoa(sample) + " is bigger than maxval")
+				}
+				world.Set(x, y, level(sample))
+			}
+		}
+	}
+	return world, nil
+}
+
+//Returns the next byte that isn't whitespace or part of a comment running from '#' to the end of the line
+func skipSpace(in *bufio.Reader) (byte, error) {
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+		case '#':
+			if _, err := in.ReadString('\n'); err != nil {
+				return 0, err
+			}
+		default:
+			return b, nil
+		}
+	}
+}
+
+//Reads a decimal number from the header, or from the raster of a P2 image, skipping whitespace and comments.
+//The whitespace after the number is left to be read.
+func readHeaderNumber(in *bufio.Reader) (int, error) {
+	b, err := skipSpace(in)
+	if err == io.EOF {
+		return 0, io.EOF
+	} else if err != nil {
+		return 0, err
+	}
+	if b < '0' || b > '9' {
+		return 0, errors.New("unexpected " + strconv.QuoteRune(rune(b)) + " where a number should be")
+	}
+	number := int(b - '0')
+	for {
+		b, err := in.ReadByte()
+		if err == io.EOF {
+			return number, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if b < '0' || b > '9' {
+			return number, in.UnreadByte()
+		}
+		number = number*10 + int(b-'0')
+		if number > 1<<30 {
+			return 0, errors.New("number in image is too big")
+		}
+	}
+}
+
+//Returns the level of the state of rule nearest to grey, so that every backend can hold it
+func nearestLevel(rule gol.Rule, grey byte) byte {
+	nearest := byte(0)
+	for state := 1; state < rule.States(); state++ {
+		level := rule.Level(state)
+		if absDiff(level, grey) < absDiff(nearest, grey) {
+			nearest = level
+		}
+	}
+	return nearest
+}
+
+func absDiff(a, b byte) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// pattern is a set of live cells read from a pattern file: RLE, plaintext or Life 1.06.
+type pattern struct {
+	//width and height come from the file's header, or from the cells if there isn't one
+	width, height int
+	//rule is the rulestring given in the file, if any
+	rule string
+	//comments holds the comment lines of the file as they were written, '#' included
+	comments []string
+	cells    []cell
+}
+
+// fileParams says where the starting world comes from and how the alive cells are written out.
+// With no input the world is read from images/ using the size in golParams.
+type fileParams struct {
+	//input is the path of a netpbm image or pattern file
+	input string
+	//offset is where the top left corner of the input goes in the world
+	offset cell
+	//threshold is the grey level at which pixels of input images become live cells, 0 snaps grey levels to the nearest states of the rule
+	threshold int
+	//format is the extension of the pattern files written out, see patternFormats
+	format string
+	//png writes png images as well as pgm images
+	png bool
+	//gif is the path of an animated gif to record, with a frame every gifEvery turns
+	gif      string
+	gifEvery int
+	//scale and palette say how png and gif images are drawn
+	scale   int
+	palette palette
+	//checkpoint is the path checkpoints are written to every checkpointEvery and on 'q', if it is given
+	checkpoint      string
+	checkpointEvery time.Duration
+	//resume is the path of a checkpoint to carry on from instead of starting from input
+	resume string
+	//soup fills the world with random cells instead of reading input
+	soup soupParams
+	//stats is the path of a csv or jsonl file to write the stats of every statsEvery turns to, if it is given
+	stats      string
+	statsEvery int
+	//http is the address the HTTP control API is served on, if it is given
+	http string
+	//headless runs without termbox, so keys aren't read from the terminal
+	headless bool
+	//view is how the world is drawn in the terminal, which headless runs never do
+	view viewMode
+	//workers are the addresses of worker processes to run the game on instead of goroutines
+	workers []string
+	//spares are worker processes that take over from any of the workers that fail
+	spares []string
+}
+
+// patternFormats lists the extensions of the pattern files that can be read and written.
+// Life 1.06 files may end in either .lif or .life.
+var patternFormats = []string{".rle", ".cells", ".lif", ".life"}
+
+//Returns the format of a pattern file, or an error if it isn't one of patternFormats
+func patternFormat(filename string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, format := range patternFormats {
+		if ext == format {
+			return ext, nil
+		}
+	}
+	return "", errors.New("unknown pattern format " + strconv.Quote(ext) + ", expected one of " + strings.Join(patternFormats, ", "))
+}
+
+// readPattern reads the pattern file at path in the format given by its extension.
+func readPattern(path string) (*pattern, error) {
+	format, err := patternFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decodePattern(file, format)
+}
+
+// decodePattern reads a pattern in the given format.
+func decodePattern(r io.Reader, format string) (*pattern, error) {
+	switch format {
+	case ".cells":
+		return readCells(r)
+	case ".lif", ".life":
+		return readLife106(r)
+	default:
+		return readRle(r)
+	}
+}
+
+// writePattern writes world in the given format, with its top left corner at origin on the plane.
+// Comments are written the way RLE files write them and are left out of Life 1.06 files.
+func writePattern(w io.Writer, format string, world *gol.World, origin cell, rule gol.Rule, comments []string) error {
+	switch format {
+	case ".cells":
+		return writeCells(w, world, comments)
+	case ".lif", ".life":
+		cells := world.Alive()
+		for i := range cells {
+			cells[i].X += origin.X
+			cells[i].Y += origin.Y
+		}
+		return writeLife106(w, cells)
+	default:
+		if origin != (cell{}) {
+			comments = append(comments, regionComment(origin))
+		}
+		return writeRle(w, world, rule, comments)
+	}
+}
+
+// writePatternFile writes world to out/filename with the extension of the given format.
+func writePatternFile(filename string, format string, world *gol.World, origin cell, rule gol.Rule, comments []string) {
+	_ = os.Mkdir("out", os.ModePerm)
+
+	file, ioError := os.Create("out/" + filename + format)
+	check(ioError)
+	defer file.Close()
+	check(writePattern(file, format, world, origin, rule, comments))
+
+	fmt.Println("File", filename+format, "output done!")
+}
+
+// writeRegion writes the smallest part of the plane holding every live cell as both a pgm and a pattern file,
+// for engines whose worlds are unbounded.
+func writeRegion(p golParams, files fileParams, alive []cell) {
+	world, origin := gol.Region(alive)
+	//appends current time to filename so they don't overwrite each other
+	filename := regionName(world, origin) + "-" + time.Now().Format("15:04:05.000000")
+	writePgm(filename, world)
+	if files.png {
+		writePngFile(filename, world, files)
+	}
+	writePatternFile(filename, files.format, world, origin, p.Rule, nil)
+}
+
+//Names a region by its size and the position of its top left corner on the plane
+func regionName(world *gol.World, origin cell) string {
+	return strconv.Itoa(world.Width()) + "x" + strconv.Itoa(world.Height()) + "@" + strconv.Itoa(origin.X) + "," + strconv.Itoa(origin.Y)
+}
+
+// place sets the cells of the pattern in world with its top left corner at offset.
+// Cells that land outside of the world are left out.
+func (pat *pattern) place(world *gol.World, offset cell) {
+	for _, c := range pat.cells {
+		x, y := c.X+offset.X, c.Y+offset.Y
+		if x >= 0 && x < world.Width() && y >= 0 && y < world.Height() {
+			world.Set(x, y, gol.Alive)
+		}
+	}
+}
+
+// cellValue lets a cell be given as a command line flag in the form x,y.
+type cellValue cell
+
+func (c *cellValue) String() string {
+	return strconv.Itoa(c.X) + "," + strconv.Itoa(c.Y)
+}
+
+func (c *cellValue) Set(s string) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return errors.New("expected x,y")
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return errors.New("expected x,y")
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return errors.New("expected x,y")
+	}
+	c.X, c.Y = x, y
+	return nil
+}
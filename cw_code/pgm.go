@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"strconv"
-	"strings"
 	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
 )
 
 func check(e error) {
@@ -15,136 +17,60 @@ func check(e error) {
 	}
 }
 
-//this writes pgm files for within a turn when s is pressed
-func writePgmTurn(p golParams, alivecells []cell) {
-	_ = os.Mkdir("out", os.ModePerm)
-
+//this writes pgm files for within a turn when s is pressed, and png files too if asked for
+func writePgmTurn(p golParams, files fileParams, world *gol.World) {
 	//appends current time to filename so they don't overwrite each other
-	filename := strconv.Itoa(p.imageWidth) + "x" + strconv.Itoa(p.imageHeight) + "-" + time.Now().Format("15:04:05.000000")
-	file, ioError := os.Create("out/" + filename + ".pgm")
-	check(ioError)
-	defer file.Close()
-
-	_, _ = file.WriteString("P5\n")
-	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
-	_, _ = file.WriteString(strconv.Itoa(p.imageWidth))
-	_, _ = file.WriteString(" ")
-	_, _ = file.WriteString(strconv.Itoa(p.imageHeight))
-	_, _ = file.WriteString("\n")
-	_, _ = file.WriteString(strconv.Itoa(255))
-	_, _ = file.WriteString("\n")
-
-	world := make([][]byte, p.imageHeight)
-	for i := range world {
-		world[i] = make([]byte, p.imageWidth)
-	}
-
-	alive := alivecells
-
-	for _, c := range alive {
-		world[c.y][c.x] = 255
-	}
-
-	for y := 0; y < p.imageHeight; y++ {
-		for x := 0; x < p.imageWidth; x++ {
-			_, ioError = file.Write([]byte{world[y][x]})
-			check(ioError)
-		}
+	filename := strconv.Itoa(world.Width()) + "x" + strconv.Itoa(world.Height()) + "-" + time.Now().Format("15:04:05.000000")
+	writePgm(filename, world)
+	if files.png {
+		writePngFile(filename, world, files)
 	}
-
-	ioError = file.Sync()
-	check(ioError)
-
-	fmt.Println("File", filename, "output done!")
 }
 
-// writePgmImage receives an array of bytes and writes it to a pgm file.
-// Note that this function is incomplete. Use the commented-out for loop to receive data from the distributor.
+// writePgmImage receives the final world and writes it to a pgm file.
 func writePgmImage(p golParams, i ioChans) {
+	filename := <-i.distributor.filename
+	world := <-i.distributor.worldOutput
+	writePgm(filename, world)
+	i.distributor.stop.Done()
+}
+
+// writePgm writes world to out/filename.pgm.
+// Each cell is written as the grey level of its state, so dying cells of Generations rules show up in grey.
+func writePgm(filename string, world *gol.World) {
 	_ = os.Mkdir("out", os.ModePerm)
 
-	filename := <-i.distributor.filename
 	file, ioError := os.Create("out/" + filename + ".pgm")
 	check(ioError)
 	defer file.Close()
 
-	_, _ = file.WriteString("P5\n")
-	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
-	_, _ = file.WriteString(strconv.Itoa(p.imageWidth))
-	_, _ = file.WriteString(" ")
-	_, _ = file.WriteString(strconv.Itoa(p.imageHeight))
-	_, _ = file.WriteString("\n")
-	_, _ = file.WriteString(strconv.Itoa(255))
-	_, _ = file.WriteString("\n")
-
-	world := make([][]byte, p.imageHeight)
-	for i := range world {
-		world[i] = make([]byte, p.imageWidth)
-	}
-
-	alive := <-i.distributor.aliveOutput
-
-	for _, c := range alive {
-		world[c.y][c.x] = 255
-	}
-
-	for y := 0; y < p.imageHeight; y++ {
-		for x := 0; x < p.imageWidth; x++ {
-			_, ioError = file.Write([]byte{world[y][x]})
-			check(ioError)
-		}
-	}
+	check(encodePgm(file, world))
 
 	ioError = file.Sync()
 	check(ioError)
 
 	fmt.Println("File", filename, "output done!")
-	i.distributor.stop.Done()
 }
 
-// readPgmImage opens a pgm file and sends its data as an array of bytes.
-func readPgmImage(p golParams, i ioChans) {
-	filename := <-i.distributor.filename
-	data, ioError := ioutil.ReadFile("images/" + filename + ".pgm")
-	check(ioError)
-
-	fields := strings.Fields(string(data))
-
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
-	}
-
-	width, _ := strconv.Atoi(fields[1])
-	if width != p.imageWidth {
-		panic("Incorrect width")
-	}
-
-	height, _ := strconv.Atoi(fields[2])
-	if height != p.imageHeight {
-		panic("Incorrect height")
-	}
-
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
-	}
-
-	image := []byte(fields[4])
-
-	for _, b := range image {
-		i.distributor.inputVal <- b
+// encodePgm writes world to w as a binary greymap, a whole row at a time through a buffer.
+func encodePgm(w io.Writer, world *gol.World) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "P5\n%d %d\n255\n", world.Width(), world.Height())
+	for y := 0; y < world.Height(); y++ {
+		if _, err := out.Write(world.Row(y)); err != nil {
+			return err
+		}
 	}
-
-	fmt.Println("File", filename, "input done!")
+	return out.Flush()
 }
 
-func pgmIo(p golParams, i ioChans) {
+func pgmIo(p golParams, files fileParams, i ioChans) {
 	for {
 		select {
 		case command := <-i.distributor.command:
 			switch command {
 			case ioInput:
-				readPgmImage(p, i)
+				readInputImage(p, files, i)
 			case ioOutput:
 				writePgmImage(p, i)
 			case ioCheckIdle:
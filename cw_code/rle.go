@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+//Lines of cells in RLE files are kept to this length, as the format asks
+const rleLineLength = 70
+
+// readRle reads a pattern in Run Length Encoded format.
+// Lines starting with '#' are kept as comments, and the header gives the size of the pattern and its rule.
+// Any letter other than b is read as a live cell, so dying states of Generations patterns come back alive.
+func readRle(r io.Reader) (*pattern, error) {
+	var pat pattern
+	scanner := bufio.NewScanner(r)
+	header := false
+	x, y := 0, 0
+	count := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			pat.comments = append(pat.comments, line)
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if !header {
+			if err := parseRleHeader(line, &pat); err != nil {
+				return nil, err
+			}
+			header = true
+			continue
+		}
+		for _, c := range line {
+			switch {
+			case c >= '0' && c <= '9':
+				count = count*10 + int(c-'0')
+				continue
+			case c == '!':
+				return &pat, pat.check()
+			case c == '$':
+				y += runLength(count)
+				x = 0
+			case c == 'b' || c == '.':
+				x += runLength(count)
+			case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+				for i := 0; i < runLength(count); i++ {
+					pat.cells = append(pat.cells, cell{X: x, Y: y})
+					x++
+				}
+			case c == ' ' || c == '\t':
+				continue
+			default:
+				return nil, errors.New("unexpected " + strconv.QuoteRune(c) + " in RLE pattern")
+			}
+			count = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !header {
+		return nil, errors.New("RLE pattern has no header")
+	}
+	//Plenty of files in the wild are missing the final '!'
+	return &pat, pat.check()
+}
+
+//A run with no count in front of it is one long
+func runLength(count int) int {
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+//Reads a header such as "x = 3, y = 3, rule = B3/S23" into pat
+func parseRleHeader(line string, pat *pattern) error {
+	for _, field := range strings.Split(line, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return errors.New("invalid RLE header " + strconv.Quote(line))
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		var err error
+		switch key {
+		case "x":
+			pat.width, err = strconv.Atoi(value)
+		case "y":
+			pat.height, err = strconv.Atoi(value)
+		case "rule":
+			pat.rule = value
+		}
+		if err != nil || pat.width < 0 || pat.height < 0 {
+			return errors.New("invalid size in RLE header " + strconv.Quote(line))
+		}
+	}
+	return nil
+}
+
+//Makes sure every cell lies within the size given in the header
+func (pat *pattern) check() error {
+	for _, c := range pat.cells {
+		if c.X >= pat.width || c.Y >= pat.height {
+			return errors.New("pattern has cells outside of its " + strconv.Itoa(pat.width) + "x" + strconv.Itoa(pat.height) + " header")
+		}
+	}
+	return nil
+}
+
+// writeRle writes world in Run Length Encoded format, with a header giving its size and rule.
+// Each comment is written on its own line before the header, with "#C " in front unless it already starts with '#'.
+func writeRle(w io.Writer, world *gol.World, rule gol.Rule, comments []string) error {
+	out := bufio.NewWriter(w)
+	for _, comment := range comments {
+		if !strings.HasPrefix(comment, "#") {
+			comment = "#C " + comment
+		}
+		out.WriteString(comment)
+		out.WriteByte('\n')
+	}
+	fmt.Fprintf(out, "x = %d, y = %d, rule = %s\n", world.Width(), world.Height(), rule)
+
+	line := 0
+	//emit writes a run of count tags, starting a new line first if it wouldn't fit
+	emit := func(count int, tag byte) {
+		run := string(tag)
+		if count > 1 {
+			run = strconv.Itoa(count) + run
+		}
+		if line+len(run) > rleLineLength {
+			out.WriteByte('\n')
+			line = 0
+		}
+		out.WriteString(run)
+		line += len(run)
+	}
+
+	//Empty rows are saved up so they can be written as one run of row ends
+	rowEnds := 0
+	for y := 0; y < world.Height(); y++ {
+		if y > 0 {
+			rowEnds++
+		}
+		x := 0
+		for x < world.Width() {
+			alive := world.Get(x, y) == gol.Alive
+			count := 1
+			for x+count < world.Width() && (world.Get(x+count, y) == gol.Alive) == alive {
+				count++
+			}
+			//Dead cells at the end of a row are left out
+			if alive || x+count < world.Width() {
+				if rowEnds > 0 {
+					emit(rowEnds, '$')
+					rowEnds = 0
+				}
+				if alive {
+					emit(count, 'o')
+				} else {
+					emit(count, 'b')
+				}
+			}
+			x += count
+		}
+	}
+	emit(1, '!')
+	out.WriteByte('\n')
+	return out.Flush()
+}
+
+//Records where the top left corner of a region lies on the plane, in the way XLife and Golly understand
+func regionComment(origin cell) string {
+	return "#P " + strconv.Itoa(origin.X) + " " + strconv.Itoa(origin.Y)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// symmetry is the symmetry a soup is generated with, named as in apgsearch.
+// The zero symmetry is C1, which has none.
+type symmetry int
+
+const (
+	c1 symmetry = iota
+	//c2 is unchanged by turning half way around
+	c2
+	//c4 is unchanged by turning a quarter of the way around
+	c4
+	//d8 is unchanged by any turn or reflection of a square
+	d8
+)
+
+var symmetryNames = []string{
+	c1: "C1",
+	c2: "C2",
+	c4: "C4",
+	d8: "D8",
+}
+
+func parseSymmetry(s string) (symmetry, error) {
+	for sym, name := range symmetryNames {
+		if strings.EqualFold(s, name) {
+			return symmetry(sym), nil
+		}
+	}
+	return c1, errors.New("unknown symmetry " + strconv.Quote(s) + ", expected C1, C2, C4 or D8")
+}
+
+func (sym symmetry) String() string {
+	if sym < 0 || int(sym) >= len(symmetryNames) {
+		return "symmetry(" + strconv.Itoa(int(sym)) + ")"
+	}
+	return symmetryNames[sym]
+}
+
+func (sym *symmetry) Set(s string) error {
+	parsed, err := parseSymmetry(s)
+	if err != nil {
+		return err
+	}
+	*sym = parsed
+	return nil
+}
+
+//Returns where the cell at x, y of a width x height soup ends up under each transformation of the symmetry,
+//starting with the cell itself
+func (sym symmetry) images(x, y, width, height int) []cell {
+	//Quarter turns and diagonal reflections only keep a square in place, which generateSoup makes sure of
+	n := width - 1
+	images := []cell{{X: x, Y: y}}
+	switch sym {
+	case c2:
+		images = append(images, cell{X: width - 1 - x, Y: height - 1 - y})
+	case c4:
+		images = append(images, cell{X: n - y, Y: x}, cell{X: n - x, Y: n - y}, cell{X: y, Y: n - x})
+	case d8:
+		images = append(images, cell{X: n - y, Y: x}, cell{X: n - x, Y: n - y}, cell{X: y, Y: n - x},
+			cell{X: n - x, Y: y}, cell{X: x, Y: n - y}, cell{X: y, Y: x}, cell{X: n - y, Y: n - x})
+	}
+	return images
+}
+
+// rectangle is part of the world, given as a command line flag in the form x,y,width,height.
+// The zero rectangle stands for the whole world.
+type rectangle struct {
+	x, y, width, height int
+}
+
+func (r *rectangle) String() string {
+	return strconv.Itoa(r.x) + "," + strconv.Itoa(r.y) + "," + strconv.Itoa(r.width) + "," + strconv.Itoa(r.height)
+}
+
+func (r *rectangle) Set(s string) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return errors.New("expected x,y,width,height")
+	}
+	var numbers [4]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return errors.New("expected x,y,width,height")
+		}
+		numbers[i] = n
+	}
+	if numbers[2] < 1 || numbers[3] < 1 {
+		return errors.New("the rectangle must be at least 1x1")
+	}
+	*r = rectangle{x: numbers[0], y: numbers[1], width: numbers[2], height: numbers[3]}
+	return nil
+}
+
+// soupParams describes a random soup to start from instead of an input file.
+// The same params always give the same soup.
+type soupParams struct {
+	on bool
+	//density is the chance of each cell being alive, from 0 to 1
+	density float64
+	seed    int64
+	//symmetry is kept within rect, which is the whole world if it is the zero rectangle
+	symmetry symmetry
+	rect     rectangle
+}
+
+// generateSoup returns a width x height world with a random soup in s.rect, and every other cell dead.
+// It returns an error if the rectangle doesn't fit in the world, or isn't square for C4 and D8 symmetry.
+func generateSoup(width, height int, s soupParams) (*gol.World, error) {
+	if s.density < 0 || s.density > 1 {
+		return nil, errors.New("the soup density must be between 0 and 1")
+	}
+	rect := s.rect
+	if rect == (rectangle{}) {
+		rect = rectangle{width: width, height: height}
+	}
+	if rect.x < 0 || rect.y < 0 || rect.width < 1 || rect.height < 1 || rect.x+rect.width > width || rect.y+rect.height > height {
+		return nil, errors.New("the soup rectangle " + rect.String() + " doesn't fit in the " + strconv.Itoa(width) + "x" + strconv.Itoa(height) + " world")
+	}
+	if (s.symmetry == c4 || s.symmetry == d8) && rect.width != rect.height {
+		return nil, errors.New(s.symmetry.String() + " symmetry needs a square soup")
+	}
+
+	world := gol.NewWorld(width, height)
+	random := rand.New(rand.NewSource(s.seed))
+	for y := 0; y < rect.height; y++ {
+		for x := 0; x < rect.width; x++ {
+			images := s.symmetry.images(x, y, rect.width, rect.height)
+			//Each set of images is decided once, by the first of them in reading order
+			first := true
+			for _, image := range images[1:] {
+				if image.Y < y || image.Y == y && image.X < x {
+					first = false
+				}
+			}
+			if !first || random.Float64() >= s.density {
+				continue
+			}
+			for _, image := range images {
+				world.Set(rect.x+image.X, rect.y+image.Y, gol.Alive)
+			}
+		}
+	}
+	return world, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+//The formats stats can be written in, by extension
+var statsFormats = map[string]func(w io.Writer, stats <-chan gol.Stats) error{
+	".csv":   encodeStatsCsv,
+	".jsonl": encodeStatsJsonl,
+}
+
+// checkStatsPath returns an error if the extension of path isn't one of statsFormats.
+func checkStatsPath(path string) error {
+	if _, ok := statsFormats[filepath.Ext(path)]; !ok {
+		return errors.New("unknown stats format " + strconv.Quote(filepath.Ext(path)) + ", expected .csv or .jsonl")
+	}
+	return nil
+}
+
+// recordStats writes every Stats from stats to path, as csv or json lines depending on its extension.
+// stats is always read until it is closed, even if the file can't be written, so that the game never waits on it.
+func recordStats(stats <-chan gol.Stats, path string) error {
+	defer func() {
+		for range stats {
+		}
+	}()
+	if err := checkStatsPath(path); err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		_ = os.MkdirAll(dir, os.ModePerm)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := statsFormats[filepath.Ext(path)](file, stats); err != nil {
+		file.Close()
+		return err
+	}
+	fmt.Println("File", path, "output done!")
+	return file.Close()
+}
+
+// encodeStatsCsv writes stats as csv, with a header naming each column.
+// The header has a population column for each worker, which is taken from the first stats.
+func encodeStatsCsv(w io.Writer, stats <-chan gol.Stats) error {
+	out := csv.NewWriter(w)
+	header := false
+	for s := range stats {
+		if !header {
+			names := []string{"turn", "population", "births", "deaths", "x", "y", "width", "height"}
+			for i := range s.Workers {
+				names = append(names, "worker_"+strconv.Itoa(i))
+			}
+			if err := out.Write(names); err != nil {
+				return err
+			}
+			header = true
+		}
+		record := []string{
+			strconv.Itoa(s.Turn), strconv.Itoa(s.Population), strconv.Itoa(s.Births), strconv.Itoa(s.Deaths),
+			strconv.Itoa(s.X), strconv.Itoa(s.Y), strconv.Itoa(s.Width), strconv.Itoa(s.Height),
+		}
+		for _, population := range s.Workers {
+			record = append(record, strconv.Itoa(population))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+//One line of a jsonl stats file
+type statsLine struct {
+	Turn       int   `json:"turn"`
+	Population int   `json:"population"`
+	Births     int   `json:"births"`
+	Deaths     int   `json:"deaths"`
+	X          int   `json:"x"`
+	Y          int   `json:"y"`
+	Width      int   `json:"width"`
+	Height     int   `json:"height"`
+	Workers    []int `json:"workers"`
+}
+
+// encodeStatsJsonl writes stats as a json object on each line.
+func encodeStatsJsonl(w io.Writer, stats <-chan gol.Stats) error {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	for s := range stats {
+		if err := encoder.Encode(statsLine(s)); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
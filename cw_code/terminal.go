@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// viewMode is how the world is drawn in the terminal while the game runs.
+// The zero viewMode draws nothing.
+type viewMode int
+
+const (
+	viewOff viewMode = iota
+	//viewHalf draws two cells, one above the other, in each character with half blocks
+	viewHalf
+	//viewBraille draws eight cells, two across and four down, in each character with braille dots
+	viewBraille
+)
+
+var viewModeNames = []string{
+	viewOff:     "off",
+	viewHalf:    "half",
+	viewBraille: "braille",
+}
+
+func parseViewMode(s string) (viewMode, error) {
+	for mode, name := range viewModeNames {
+		if strings.EqualFold(s, name) {
+			return viewMode(mode), nil
+		}
+	}
+	return viewOff, errors.New("unknown view " + strconv.Quote(s) + ", expected off, half or braille")
+}
+
+func (m viewMode) String() string {
+	if m < 0 || int(m) >= len(viewModeNames) {
+		return "viewMode(" + strconv.Itoa(int(m)) + ")"
+	}
+	return viewModeNames[m]
+}
+
+func (m *viewMode) Set(s string) error {
+	parsed, err := parseViewMode(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+//The number of cells across and down drawn in each character, before zooming out
+func (m viewMode) dots() (int, int) {
+	if m == viewBraille {
+		return 2, 4
+	}
+	return 1, 2
+}
+
+//The bits of each braille dot, by row and then column, added to U+2800
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+//How often the terminal view is redrawn, and how far in it can be zoomed out
+const (
+	viewFps     = 15
+	maxViewZoom = 64
+)
+
+// terminalView is the part of the world shown in the terminal.
+// x and y are the cell in the top left corner, and each dot drawn stands for a zoom x zoom square of cells,
+// which is shown if any of them is alive.
+type terminalView struct {
+	mode viewMode
+	x, y int
+	zoom int
+}
+
+//The number of cells across and down each character covers
+func (v *terminalView) span() (int, int) {
+	across, down := v.mode.dots()
+	return across * v.zoom, down * v.zoom
+}
+
+// clamp keeps the view over the world when it is columns x rows characters in size,
+// leaving it at the top left of worlds that fit.
+func (v *terminalView) clamp(width, height, columns, rows int) {
+	across, down := v.span()
+	v.x = clampInt(v.x, 0, width-columns*across)
+	v.y = clampInt(v.y, 0, height-rows*down)
+}
+
+//Returns n kept between low and high, or low if high is lower
+func clampInt(n, low, high int) int {
+	if n > high {
+		n = high
+	}
+	if n < low {
+		n = low
+	}
+	return n
+}
+
+// key pans or zooms the view for a key read by getKeyboardCommand, returning false for keys it doesn't use.
+// The arrow keys move a quarter of the screen, + and - zoom in and out around the middle of the screen
+// and v switches between half blocks and braille.
+func (v *terminalView) key(key rune, columns, rows int) bool {
+	across, down := v.span()
+	centreX, centreY := v.x+columns*across/2, v.y+rows*down/2
+	switch key {
+	case rune(termbox.KeyArrowLeft):
+		v.x -= max(1, columns*across/4)
+	case rune(termbox.KeyArrowRight):
+		v.x += max(1, columns*across/4)
+	case rune(termbox.KeyArrowUp):
+		v.y -= max(1, rows*down/4)
+	case rune(termbox.KeyArrowDown):
+		v.y += max(1, rows*down/4)
+	case '+', '=':
+		if v.zoom > 1 {
+			v.zoom /= 2
+		}
+	case '-':
+		if v.zoom < maxViewZoom {
+			v.zoom *= 2
+		}
+	case 'v':
+		if v.mode == viewHalf {
+			v.mode = viewBraille
+		} else {
+			v.mode = viewHalf
+		}
+	default:
+		return false
+	}
+	if key == '+' || key == '=' || key == '-' || key == 'v' {
+		across, down = v.span()
+		v.x, v.y = centreX-columns*across/2, centreY-rows*down/2
+	}
+	return true
+}
+
+//Returns the larger of a and b
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// render draws the part of world the view covers as columns x rows characters.
+// Characters past the edges of the world are left blank.
+func (v *terminalView) render(world *gol.World, columns, rows int) [][]rune {
+	across, down := v.mode.dots()
+	//dot reports whether any cell of the square a dot stands for is alive
+	dot := func(dx, dy int) bool {
+		for y := v.y + dy*v.zoom; y < v.y+(dy+1)*v.zoom && y < world.Height(); y++ {
+			row := world.Row(y)
+			for x := v.x + dx*v.zoom; x < v.x+(dx+1)*v.zoom && x < world.Width(); x++ {
+				if row[x] == gol.Alive {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	lines := make([][]rune, rows)
+	for cy := range lines {
+		lines[cy] = make([]rune, columns)
+		for cx := range lines[cy] {
+			var char rune
+			if v.mode == viewBraille {
+				for dy := 0; dy < down; dy++ {
+					for dx := 0; dx < across; dx++ {
+						if dot(cx*across+dx, cy*down+dy) {
+							char |= brailleDots[dy][dx]
+						}
+					}
+				}
+				if char != 0 {
+					char += 0x2800
+				}
+			} else {
+				top, bottom := dot(cx, cy*2), dot(cx, cy*2+1)
+				switch {
+				case top && bottom:
+					char = '█'
+				case top:
+					char = '▀'
+				case bottom:
+					char = '▄'
+				}
+			}
+			if char == 0 {
+				char = ' '
+			}
+			lines[cy][cx] = char
+		}
+	}
+	return lines
+}
+
+// status is the line shown below the world.
+func (v *terminalView) status(turn, alive int, rate float64, paused bool) string {
+	line := fmt.Sprintf("Turn %d  Alive %d  %.0f turns/s  %d,%d 1:%d", turn, alive, rate, v.x, v.y, v.zoom)
+	if paused {
+		line += "  Paused"
+	}
+	return line + "  (arrows pan, +/- zoom, v switches view)"
+}
+
+// viewWorld draws game in the terminal, which termbox must have been initialised for, until it finishes.
+// The world is drawn from a snapshot taken each time the view is redrawn, while the number of alive cells is counted
+// by the workers, which for unbounded engines includes cells outside of the world.
+// Keys are taken from keys to move the view around.
+// Output printed while the view is up is drawn over once a second.
+// Unbounded engines show the part of the plane the world covers.
+func viewWorld(game *gol.Game, mode viewMode, keys <-chan rune) {
+	v := &terminalView{mode: mode, zoom: 1}
+	feed := newViewerFeed(game)
+	ticker := time.NewTicker(time.Second / viewFps)
+	defer ticker.Stop()
+	lastTurn, lastTime, rate := -1, time.Now(), 0.0
+	frames := 0
+	for {
+		columns, rows := termbox.Size()
+		//The bottom line is kept for the status bar
+		rows = max(rows-1, 0)
+		select {
+		case key := <-keys:
+			v.key(key, columns, rows)
+		case <-ticker.C:
+		case <-game.Done():
+			return
+		}
+
+		state, _ := feed.current()
+		_, alive := game.Count()
+		paused := game.Paused()
+		now := time.Now()
+		if lastTurn >= 0 && state.Turn != lastTurn {
+			rate = float64(state.Turn-lastTurn) / now.Sub(lastTime).Seconds()
+		}
+		if state.Turn != lastTurn || paused {
+			lastTurn, lastTime = state.Turn, now
+		}
+		if paused {
+			rate = 0
+		}
+
+		v.clamp(state.World.Width(), state.World.Height(), columns, rows)
+		for y, line := range v.render(state.World, columns, rows) {
+			for x, char := range line {
+				termbox.SetCell(x, y, char, termbox.ColorDefault, termbox.ColorDefault)
+			}
+		}
+		status := []rune(v.status(state.Turn, alive, rate, paused))
+		for x := 0; x < columns; x++ {
+			char := ' '
+			if x < len(status) {
+				char = status[x]
+			}
+			termbox.SetCell(x, rows, char, termbox.AttrReverse, termbox.ColorDefault)
+		}
+		frames++
+		if frames%viewFps == 0 {
+			termbox.Sync()
+		} else {
+			termbox.Flush()
+		}
+	}
+}
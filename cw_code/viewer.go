@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/binary"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+//The kinds of message sent to viewers
+const (
+	//viewerWorld replaces the whole world, listing every live cell as a birth
+	viewerWorld byte = iota
+	//viewerDiff lists the cells born and died since the last message
+	viewerDiff
+)
+
+//The number of bytes before the cells of a viewer message
+const viewerHeader = 25
+
+//The frame rates viewers may ask for, and the one they get if they don't
+const (
+	defaultViewerFps = 10
+	maxViewerFps     = 60
+)
+
+//How long a viewer has to take each message before it is dropped
+const viewerTimeout = 10 * time.Second
+
+// viewerFeed takes the snapshots of a game that every viewer streams, so that the workers are stopped for a snapshot
+// at most maxViewerFps times a second however many viewers there are.
+type viewerFeed struct {
+	game *gol.Game
+	mu   sync.Mutex
+	//state is the last snapshot, which was taken at taken
+	state gol.State
+	taken time.Time
+	//final is set once state is the world the game finished with
+	final bool
+}
+
+func newViewerFeed(game *gol.Game) *viewerFeed {
+	return &viewerFeed{game: game}
+}
+
+// current returns the latest snapshot of the game, taking a new one if the last is older than a frame at maxViewerFps,
+// and whether it is the final world.
+func (f *viewerFeed) current() (gol.State, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.final && time.Since(f.taken) >= time.Second/maxViewerFps {
+		//Once the game has finished State is the final world, which never needs taking again
+		select {
+		case <-f.game.Done():
+			f.final = true
+		default:
+		}
+		f.state, f.taken = f.game.State(), time.Now()
+	}
+	return f.state, f.final
+}
+
+// viewerMessage encodes the change from previous to current for viewers.
+// If previous is nil the whole of current is sent instead.
+// Messages are little endian, starting with a byte giving their kind followed by six uint32s:
+// the turn, width, height, population, and the number of cells born and died.
+// The index y*width+x of each cell born and then each cell died follows as a uint32.
+// Dying cells of Generations rules count as dead.
+func viewerMessage(previous *gol.World, turn int, current *gol.World) []byte {
+	width, height := current.Width(), current.Height()
+	kind := viewerDiff
+	if previous == nil {
+		kind = viewerWorld
+	}
+	population := 0
+	var births, deaths []uint32
+	for y := 0; y < height; y++ {
+		row := current.Row(y)
+		var before []byte
+		if previous != nil {
+			before = previous.Row(y)
+		}
+		for x, state := range row {
+			alive := state == gol.Alive
+			if alive {
+				population++
+			}
+			wasAlive := before != nil && before[x] == gol.Alive
+			if alive && !wasAlive {
+				births = append(births, uint32(y*width+x))
+			} else if wasAlive && !alive {
+				deaths = append(deaths, uint32(y*width+x))
+			}
+		}
+	}
+
+	message := make([]byte, viewerHeader+4*(len(births)+len(deaths)))
+	message[0] = kind
+	for i, n := range []int{turn, width, height, population, len(births), len(deaths)} {
+		binary.LittleEndian.PutUint32(message[1+4*i:], uint32(n))
+	}
+	cells := message[viewerHeader:]
+	for i, index := range append(births, deaths...) {
+		binary.LittleEndian.PutUint32(cells[4*i:], index)
+	}
+	return message
+}
+
+// streamWorld sends the world to a viewer at most fps times a second until the game finishes or the viewer goes away,
+// starting with the whole world and then only the cells that have changed.
+// Turns that pass between messages aren't sent, and nothing is sent while the game is paused.
+// Unbounded engines send the part of the plane the world covers.
+func streamWorld(ws *websocket, feed *viewerFeed, fps int) {
+	defer ws.close()
+	pings, closed := ws.listen()
+	ticker := time.NewTicker(time.Second / time.Duration(fps))
+	defer ticker.Stop()
+
+	var previous *gol.World
+	turn := -1
+	for {
+		select {
+		case <-closed:
+			return
+		case payload := <-pings:
+			if ws.pong(payload) != nil {
+				return
+			}
+			continue
+		case <-ticker.C:
+		}
+
+		//The final world is the last thing sent
+		state, finished := feed.current()
+		if state.Turn != turn {
+			_ = ws.conn.SetWriteDeadline(time.Now().Add(viewerTimeout))
+			if ws.send(viewerMessage(previous, state.Turn, state.World)) != nil {
+				return
+			}
+			previous, turn = state.World, state.Turn
+		}
+		if finished {
+			return
+		}
+	}
+}
+
+// serveViewer upgrades a request to a WebSocket and streams the game feed is taken from to it,
+// at the frame rate given by the fps parameter.
+func serveViewer(w http.ResponseWriter, r *http.Request, feed *viewerFeed) {
+	fps := defaultViewerFps
+	if value := r.URL.Query().Get("fps"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxViewerFps {
+			http.Error(w, "fps must be a number from 1 to "+strconv.Itoa(maxViewerFps), http.StatusBadRequest)
+			return
+		}
+		fps = n
+	}
+	ws, err := upgradeWebsocket(w, r)
+	if err != nil {
+		return
+	}
+	streamWorld(ws, feed, fps)
+}
+
+// viewerPage is the viewer served at /, which draws the world on a canvas as it is streamed from /live.
+// It doesn't load anything else, so that it works without an internet connection.
+const viewerPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Game of Life</title>
+<style>
+	body { margin: 0; background: #202020; color: #e0e0e0; font: 14px monospace; }
+	#status { padding: 8px; }
+	#status select { font: inherit; }
+	canvas { display: block; margin: 0 8px; image-rendering: pixelated; image-rendering: crisp-edges; background: #000; }
+</style>
+</head>
+<body>
+<div id="status">
+	<span id="info">Connecting...</span>
+	&nbsp; fps <select id="fps"><option>1</option><option>5</option><option selected>10</option><option>30</option><option>60</option></select>
+</div>
+<canvas id="world" width="1" height="1"></canvas>
+<script>
+"use strict";
+var canvas = document.getElementById("world");
+var context = canvas.getContext("2d");
+var info = document.getElementById("info");
+var fps = document.getElementById("fps");
+var image = null;
+var socket = null;
+var lastTurn = 0, lastTime = 0, turnsPerSecond = 0;
+
+function resize() {
+	if (!image) {
+		return;
+	}
+	//Cells are drawn as whole pixels, as big as fit in the window
+	var scale = Math.max(1, Math.floor(Math.min((window.innerWidth - 16) / image.width, (window.innerHeight - 48) / image.height)));
+	canvas.style.width = image.width * scale + "px";
+	canvas.style.height = image.height * scale + "px";
+}
+
+function paint(index, alive) {
+	var value = alive ? 255 : 0;
+	var i = index * 4;
+	image.data[i] = image.data[i + 1] = image.data[i + 2] = value;
+	image.data[i + 3] = 255;
+}
+
+function receive(event) {
+	var view = new DataView(event.data);
+	var kind = view.getUint8(0);
+	var turn = view.getUint32(1, true), width = view.getUint32(5, true), height = view.getUint32(9, true);
+	var population = view.getUint32(13, true), births = view.getUint32(17, true), deaths = view.getUint32(21, true);
+	if (kind === 0 || !image || image.width !== width || image.height !== height) {
+		canvas.width = width;
+		canvas.height = height;
+		image = context.createImageData(width, height);
+		for (var i = 0; i < width * height; i++) {
+			paint(i, false);
+		}
+		resize();
+	}
+	var offset = 25;
+	for (var b = 0; b < births; b++, offset += 4) {
+		paint(view.getUint32(offset, true), true);
+	}
+	for (var d = 0; d < deaths; d++, offset += 4) {
+		paint(view.getUint32(offset, true), false);
+	}
+	context.putImageData(image, 0, 0);
+
+	var now = performance.now();
+	if (lastTime && now > lastTime) {
+		turnsPerSecond = (turn - lastTurn) * 1000 / (now - lastTime);
+	}
+	lastTurn = turn;
+	lastTime = now;
+	info.textContent = "Turn " + turn + "   Alive " + population + "   " + width + "x" + height +
+		"   " + Math.round(turnsPerSecond) + " turns/s";
+}
+
+function connect() {
+	if (socket) {
+		socket.onclose = null;
+		socket.close();
+	}
+	var scheme = location.protocol === "https:" ? "wss://" : "ws://";
+	socket = new WebSocket(scheme + location.host + "/live?fps=" + fps.value);
+	socket.binaryType = "arraybuffer";
+	socket.onmessage = receive;
+	socket.onclose = function () {
+		info.textContent += "   (disconnected)";
+	};
+	lastTime = 0;
+}
+
+fps.onchange = connect;
+window.onresize = resize;
+connect();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+//The GUID every WebSocket server appends to the client's key, from RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+//The opcodes of the WebSocket frames that are sent or understood
+const (
+	opBinary byte = 0x2
+	opClose  byte = 0x8
+	opPing   byte = 0x9
+	opPong   byte = 0xa
+)
+
+//Messages from clients bigger than this are refused, as the viewer never expects more than a close frame
+const maxClientFrame = 1 << 16
+
+// websocket is the server's end of a WebSocket connection, made by upgradeWebsocket.
+// Only one goroutine may write to it at a time, and only one may read.
+type websocket struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+//Returns the Sec-WebSocket-Accept header that answers a client's Sec-WebSocket-Key
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+//Reports whether a comma separated header holds token, ignoring case
+func headerHas(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeWebsocket carries out the opening handshake of RFC 6455 and takes over the connection.
+// If the request isn't a WebSocket handshake it replies with an error and returns it.
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*websocket, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !headerHas(r.Header, "Connection", "upgrade") ||
+		!headerHas(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("not a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported WebSocket version")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "can't take over the connection", http.StatusInternalServerError)
+		return nil, errors.New("connection can't be hijacked")
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &websocket{conn: conn, reader: buffered.Reader}, nil
+}
+
+//Writes a single unfragmented frame. Frames sent by servers aren't masked.
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n < 1<<16:
+		header[1] = 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+//Reads a single frame, unmasking its payload if it is masked.
+//Frames longer than limit are refused.
+func readFrame(r io.Reader, limit uint64) (opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > limit {
+		return 0, nil, errors.New("WebSocket frame too big")
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+// send writes a binary message.
+func (ws *websocket) send(message []byte) error {
+	return writeFrame(ws.conn, opBinary, message)
+}
+
+// listen reads frames from the client on its own goroutine until the client closes the connection or goes away,
+// which closes the returned channel. The payload of every ping is sent on pings, to be answered with a pong.
+// Everything else the client sends is ignored.
+func (ws *websocket) listen() (pings <-chan []byte, closed <-chan bool) {
+	pinged := make(chan []byte, 1)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for {
+			opcode, payload, err := readFrame(ws.reader, maxClientFrame)
+			if err != nil || opcode == opClose {
+				return
+			}
+			if opcode == opPing {
+				select {
+				case pinged <- payload:
+				default:
+					//A pong only has to answer the latest ping
+				}
+			}
+		}
+	}()
+	return pinged, done
+}
+
+// pong answers a ping.
+func (ws *websocket) pong(payload []byte) error {
+	return writeFrame(ws.conn, opPong, payload)
+}
+
+// close sends a close frame and closes the connection.
+func (ws *websocket) close() error {
+	_ = writeFrame(ws.conn, opClose, nil)
+	return ws.conn.Close()
+}